@@ -0,0 +1,262 @@
+// Package bundle reads and writes backup bundles: gzip-compressed tar archives
+// of todo data files with a manifest of SHA-256 checksums.
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"todo-cli/models"
+)
+
+const (
+	// ManifestFile is the archive entry listing every other entry
+	ManifestFile = "manifest.json"
+	// RepositoryFile is the archive entry holding the repository
+	RepositoryFile = "repository.json"
+
+	formatVersion = 1
+)
+
+// File is a data file stored in a bundle
+type File struct {
+	Path string      // Slash-separated path relative to the data directory
+	Mode os.FileMode // Permission bits
+	Data []byte
+}
+
+// FileEntry describes a file in the manifest
+type FileEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest lists the files of a bundle with their checksums
+type Manifest struct {
+	Version   int         `json:"version"`
+	CreatedAt time.Time   `json:"created_at"`
+	Files     []FileEntry `json:"files"`
+}
+
+// Bundle is a bundle read into memory
+type Bundle struct {
+	Manifest Manifest
+	Files    []File
+}
+
+// Write creates a bundle at path from files, replacing it atomically
+func Write(target string, files []File) error {
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	manifest := Manifest{Version: formatVersion, CreatedAt: time.Now()}
+	for _, file := range files {
+		sum := sha256.Sum256(file.Data)
+		manifest.Files = append(manifest.Files, FileEntry{
+			Path:   file.Path,
+			Size:   int64(len(file.Data)),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	entries := append([]File{{Path: ManifestFile, Mode: 0644, Data: manifestData}}, files...)
+	for _, file := range entries {
+		header := &tar.Header{
+			Name:    file.Path,
+			Mode:    int64(file.Mode.Perm()),
+			Size:    int64(len(file.Data)),
+			ModTime: manifest.CreatedAt,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+		if _, err := tw.Write(file.Data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(target); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	// A unique name keeps concurrent writers of the same bundle apart
+	tmp, err := os.CreateTemp(filepath.Dir(target), filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// CreateTemp makes the file with mode 0600
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return nil
+}
+
+// Read loads a bundle without checking it; use Verify before trusting its contents
+func Read(source string) (*Bundle, error) {
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	b := &Bundle{}
+	foundManifest := false
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("corrupt bundle: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt bundle: %w", err)
+		}
+
+		if header.Name == ManifestFile {
+			if err := json.Unmarshal(data, &b.Manifest); err != nil {
+				return nil, fmt.Errorf("corrupt manifest: %w", err)
+			}
+			foundManifest = true
+			continue
+		}
+
+		b.Files = append(b.Files, File{Path: header.Name, Mode: os.FileMode(header.Mode).Perm(), Data: data})
+	}
+
+	if !foundManifest {
+		return nil, fmt.Errorf("bundle has no %s", ManifestFile)
+	}
+
+	return b, nil
+}
+
+// Verify checks every file against the manifest and returns the problems found
+func (b *Bundle) Verify() []error {
+	var problems []error
+
+	if b.Manifest.Version > formatVersion {
+		problems = append(problems, fmt.Errorf("bundle format version %d is newer than supported version %d", b.Manifest.Version, formatVersion))
+	}
+
+	files := make(map[string]File)
+	for _, file := range b.Files {
+		files[file.Path] = file
+		if !safePath(file.Path) {
+			problems = append(problems, fmt.Errorf("%s: unsafe path", file.Path))
+		}
+	}
+
+	listed := make(map[string]bool)
+	for _, entry := range b.Manifest.Files {
+		listed[entry.Path] = true
+		file, ok := files[entry.Path]
+		if !ok {
+			problems = append(problems, fmt.Errorf("%s: missing", entry.Path))
+			continue
+		}
+		sum := sha256.Sum256(file.Data)
+		if int64(len(file.Data)) != entry.Size || hex.EncodeToString(sum[:]) != entry.SHA256 {
+			problems = append(problems, fmt.Errorf("%s: checksum mismatch", entry.Path))
+		}
+	}
+
+	for _, file := range b.Files {
+		if !listed[file.Path] {
+			problems = append(problems, fmt.Errorf("%s: not in manifest", file.Path))
+		}
+	}
+
+	if _, ok := files[RepositoryFile]; !ok {
+		problems = append(problems, fmt.Errorf("%s: missing", RepositoryFile))
+	} else if _, err := b.Repository(); err != nil {
+		problems = append(problems, err)
+	}
+
+	return problems
+}
+
+// Repository decodes the repository stored in the bundle
+func (b *Bundle) Repository() (*models.Repository, error) {
+	for _, file := range b.Files {
+		if file.Path == RepositoryFile {
+			var repo models.Repository
+			if err := json.Unmarshal(file.Data, &repo); err != nil {
+				return nil, fmt.Errorf("%s: %w", RepositoryFile, err)
+			}
+			return &repo, nil
+		}
+	}
+	return nil, fmt.Errorf("bundle has no %s", RepositoryFile)
+}
+
+// IsBundle reports whether a path holds, or should hold, a bundle: existing
+// files are recognized by their gzip header, new ones by a .bundle, .tgz or
+// .tar.gz extension
+func IsBundle(target string) bool {
+	f, err := os.Open(target)
+	if err != nil {
+		lower := strings.ToLower(target)
+		return strings.HasSuffix(lower, ".bundle") || strings.HasSuffix(lower, ".tgz") || strings.HasSuffix(lower, ".tar.gz")
+	}
+	defer f.Close()
+
+	magic := make([]byte, 2)
+	n, _ := io.ReadFull(f, magic)
+	return n == 2 && magic[0] == 0x1f && magic[1] == 0x8b
+}
+
+// safePath reports whether an archive path stays inside the data directory
+func safePath(p string) bool {
+	clean := path.Clean(p)
+	return clean == p && !path.IsAbs(clean) && clean != ".." && !strings.HasPrefix(clean, "../")
+}
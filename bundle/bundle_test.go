@@ -0,0 +1,46 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteRoundTrip(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "team.bundle")
+	if err := Write(target, []File{{Path: "repository.json", Mode: 0644, Data: []byte("{}")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Read(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Files) != 1 || string(b.Files[0].Data) != "{}" {
+		t.Errorf("files = %+v", b.Files)
+	}
+	if info, err := os.Stat(target); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("bundle mode = %v, %v, want 0644", info, err)
+	}
+}
+
+func TestWriteRemovesTempFileOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	// Renaming over a non-empty directory fails
+	target := filepath.Join(dir, "team.bundle")
+	if err := os.MkdirAll(filepath.Join(target, "taken"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Write(target, nil); err == nil {
+		t.Fatal("Write over a directory succeeded")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want the temporary file removed", len(entries))
+	}
+}
@@ -6,6 +6,7 @@ import (
     "github.com/spf13/cobra"
     "todo-cli/models"
     "todo-cli/remote"
+    "todo-cli/storage"
 )
 
 var BranchCmd = &cobra.Command{
@@ -15,10 +16,12 @@ var BranchCmd = &cobra.Command{
 
 var branchCreateCmd = &cobra.Command{
     Use:   "create [branch_name]",
-    Short: "Create a new branch",
+    Short: "Create a new branch from the current branch, another branch or a commit",
     Args:  cobra.ExactArgs(1),
     Run: func(cmd *cobra.Command, args []string) {
         branchName := args[0]
+        from, _ := cmd.Flags().GetString("from")
+        empty, _ := cmd.Flags().GetBool("empty")
         
         repo, err := storage_instance.LoadRepository()
         if err != nil {
@@ -26,29 +29,30 @@ var branchCreateCmd = &cobra.Command{
             return
         }
         
-        // Check if branch already exists
-        if storage_instance.GetBranchByName(repo, branchName) != nil {
-            fmt.Printf("Branch '%s' already exists\n", branchName)
-            return
+        // An empty branch starts from nothing, otherwise from the current branch by default
+        if empty {
+            from = ""
+        } else if from == "" {
+            from = repo.CurrentBranch
         }
         
-        // Create new branch
-        newBranch := models.Branch{
-            Name:      branchName,
-            CreatedAt: time.Now(),
-            IsActive:  false,
-            Todos:     []models.Todo{},
+        newBranch, err := storage.CreateBranch(repo, branchName, from)
+        if err != nil {
+            fmt.Printf("Error: %v\n", err)
+            return
         }
         
-        repo.Branches = append(repo.Branches, newBranch)
-        
         err = storage_instance.SaveRepository(repo)
         if err != nil {
             fmt.Printf("Error saving repository: %v\n", err)
             return
         }
         
-        fmt.Printf("Created branch: %s\n", branchName)
+        if newBranch.Parent != "" {
+            fmt.Printf("Created branch: %s from %s (%d todos)\n", branchName, from, len(newBranch.Todos))
+        } else {
+            fmt.Printf("Created branch: %s\n", branchName)
+        }
     },
 }
 
@@ -76,10 +80,31 @@ var branchListCmd = &cobra.Command{
 var branchSwitchCmd = &cobra.Command{
     Use:   "switch [branch_name]",
     Short: "Switch to a branch",
-    Args:  cobra.ExactArgs(1),
+    Long:  "Switch to a branch. With --git, switch to the branch named after the current git branch, creating it from the current branch if needed.",
+    Args:  cobra.MaximumNArgs(1),
     Run: func(cmd *cobra.Command, args []string) {
-        branchName := args[0]
         sync, _ := cmd.Flags().GetBool("sync")
+        followGit, _ := cmd.Flags().GetBool("git")
+        noVerify, _ := cmd.Flags().GetBool("no-verify")
+        
+        var branchName string
+        switch {
+        case followGit && len(args) > 0:
+            fmt.Println("Cannot use a branch name together with --git")
+            return
+        case followGit:
+            gitBranch, err := currentGitBranch()
+            if err != nil {
+                fmt.Printf("Error: %v\n", err)
+                return
+            }
+            branchName = gitBranch
+        case len(args) == 1:
+            branchName = args[0]
+        default:
+            fmt.Println("Specify a branch name or --git")
+            return
+        }
         
         repo, err := storage_instance.LoadRepository()
         if err != nil {
@@ -87,6 +112,15 @@ var branchSwitchCmd = &cobra.Command{
             return
         }
         
+        if followGit && storage_instance.GetBranchByName(repo, branchName) == nil {
+            if parent := storage_instance.GetCurrentBranch(repo); parent != nil {
+                repo.Branches = append(repo.Branches, storage.ForkBranch(repo, parent, branchName))
+            } else {
+                repo.Branches = append(repo.Branches, models.Branch{Name: branchName, CreatedAt: time.Now(), Todos: []models.Todo{}})
+            }
+            fmt.Printf("Created branch: %s to follow git\n", branchName)
+        }
+        
         // Check if branch exists locally
         if storage_instance.GetBranchByName(repo, branchName) == nil {
             // Try to pull from remote if sync is enabled
@@ -119,6 +153,17 @@ var branchSwitchCmd = &cobra.Command{
             }
         }
         
+        previousBranch := repo.CurrentBranch
+        if !noVerify {
+            err = runHook(hookPreSwitch, map[string]interface{}{
+                "previous": previousBranch,
+                "branch":   branchName,
+            })
+            if err != nil {
+                fmt.Printf("Switch aborted: %v\n", err)
+                return
+            }
+        }
         repo.CurrentBranch = branchName
         
         err = storage_instance.SaveRepository(repo)
@@ -129,6 +174,11 @@ var branchSwitchCmd = &cobra.Command{
         
         fmt.Printf("Switched to branch: %s\n", branchName)
         
+        runPostHook(hookPostSwitch, map[string]interface{}{
+            "previous": previousBranch,
+            "branch":   branchName,
+        })
+        
         // Auto-sync if requested
         if sync && len(repo.Remotes) > 0 {
             fmt.Println("Syncing with remote...")
@@ -137,7 +187,7 @@ var branchSwitchCmd = &cobra.Command{
             
             remoteRepo, err := remoteService.PullRepository(targetRemote)
             if err == nil {
-                mergedRepo := remoteService.MergeRepositories(repo, remoteRepo)
+                mergedRepo, _ := remoteService.MergeRepositories(repo, remoteRepo)
                 storage_instance.SaveRepository(mergedRepo)
                 fmt.Println("Synced with remote")
             }
@@ -145,11 +195,149 @@ var branchSwitchCmd = &cobra.Command{
     },
 }
 
+var branchDeleteCmd = &cobra.Command{
+    Use:   "delete [branch_name]",
+    Short: "Delete a branch",
+    Args:  cobra.ExactArgs(1),
+    Run: func(cmd *cobra.Command, args []string) {
+        branchName := args[0]
+        force, _ := cmd.Flags().GetBool("force")
+        
+        repo, err := storage_instance.LoadRepository()
+        if err != nil {
+            fmt.Printf("Error loading repository: %v\n", err)
+            return
+        }
+        
+        if err := storage.DeleteBranch(repo, branchName, force); err != nil {
+            fmt.Printf("Error: %v\n", err)
+            return
+        }
+        
+        err = storage_instance.SaveRepository(repo)
+        if err != nil {
+            fmt.Printf("Error saving repository: %v\n", err)
+            return
+        }
+        
+        fmt.Printf("Deleted branch '%s'\n", branchName)
+    },
+}
+
+var branchRenameCmd = &cobra.Command{
+    Use:   "rename [old_name] [new_name]",
+    Short: "Rename a branch",
+    Args:  cobra.ExactArgs(2),
+    Run: func(cmd *cobra.Command, args []string) {
+        oldName, newName := args[0], args[1]
+        force, _ := cmd.Flags().GetBool("force")
+        
+        repo, err := storage_instance.LoadRepository()
+        if err != nil {
+            fmt.Printf("Error loading repository: %v\n", err)
+            return
+        }
+        
+        if _, err := storage.RenameBranch(repo, oldName, newName, force); err != nil {
+            fmt.Printf("Error: %v\n", err)
+            return
+        }
+        
+        err = storage_instance.SaveRepository(repo)
+        if err != nil {
+            fmt.Printf("Error saving repository: %v\n", err)
+            return
+        }
+        
+        if err := storage_instance.RenameBranchInStates(oldName, newName); err != nil {
+            fmt.Printf("Warning: %v\n", err)
+        }
+        
+        fmt.Printf("Renamed branch '%s' to '%s'\n", oldName, newName)
+    },
+}
+
+var branchCopyCmd = &cobra.Command{
+    Use:   "copy [source_branch] [new_branch]",
+    Short: "Copy a branch with its todos and commits",
+    Args:  cobra.ExactArgs(2),
+    Run: func(cmd *cobra.Command, args []string) {
+        sourceName, newName := args[0], args[1]
+        
+        repo, err := storage_instance.LoadRepository()
+        if err != nil {
+            fmt.Printf("Error loading repository: %v\n", err)
+            return
+        }
+        
+        source := storage_instance.GetBranchByName(repo, sourceName)
+        if source == nil {
+            fmt.Printf("Branch '%s' does not exist\n", sourceName)
+            return
+        }
+        
+        if storage_instance.GetBranchByName(repo, newName) != nil {
+            fmt.Printf("Branch '%s' already exists\n", newName)
+            return
+        }
+        
+        newBranch := models.Branch{
+            Name:      newName,
+            CreatedAt: time.Now(),
+            IsActive:  false,
+            Todos:     []models.Todo{},
+            Parent:    source.Parent,
+            ForkPoint: source.ForkPoint,
+            Base:      source.Base,
+        }
+        for name, mergeBase := range source.MergeBases {
+            if newBranch.MergeBases == nil {
+                newBranch.MergeBases = make(map[string][]models.Todo)
+            }
+            newBranch.MergeBases[name] = mergeBase
+        }
+        for _, todo := range source.Todos {
+            todo.BranchName = newName
+            newBranch.Todos = append(newBranch.Todos, todo)
+        }
+        
+        // Copy commits under new IDs so both branches keep their own history
+        var copiedCommits []models.Commit
+        for _, commit := range repo.Commits {
+            if commit.Branch == sourceName {
+                commit.ID = storage.GenerateCommitID(commit.Message, newName+commit.ID)
+                commit.Branch = newName
+                copiedCommits = append(copiedCommits, commit)
+            }
+        }
+        
+        repo.Branches = append(repo.Branches, newBranch)
+        repo.Commits = append(repo.Commits, copiedCommits...)
+        
+        err = storage_instance.SaveRepository(repo)
+        if err != nil {
+            fmt.Printf("Error saving repository: %v\n", err)
+            return
+        }
+        
+        fmt.Printf("Copied branch '%s' to '%s' (%d todos, %d commits)\n", sourceName, newName, len(newBranch.Todos), len(copiedCommits))
+    },
+}
+
 func init() {
     // Add flags
     branchSwitchCmd.Flags().BoolP("sync", "s", false, "Sync with remote when switching branches")
+    branchSwitchCmd.Flags().Bool("git", false, "Follow the current git branch, creating a todo branch with the same name")
+    branchSwitchCmd.Flags().Bool("no-verify", false, "Skip the pre-switch hook")
+    branchCreateCmd.Flags().String("from", "", "Branch or commit to start from (default: current branch)")
+    branchCreateCmd.Flags().Bool("empty", false, "Create a branch with no todos and no parent")
+    branchDeleteCmd.Flags().BoolP("force", "f", false, "Delete even if the branch has unmerged todos or is main")
+    branchRenameCmd.Flags().BoolP("force", "f", false, "Rename even if the branch is main")
     
     BranchCmd.AddCommand(branchCreateCmd)
     BranchCmd.AddCommand(branchListCmd)
     BranchCmd.AddCommand(branchSwitchCmd)
+    BranchCmd.AddCommand(branchDeleteCmd)
+    BranchCmd.AddCommand(branchRenameCmd)
+    BranchCmd.AddCommand(branchCopyCmd)
 }
\ No newline at end of file
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"todo-cli/bundle"
+
+	"github.com/spf13/cobra"
+)
+
+var BundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Back up and restore all todo data as a single file",
+	Long: `Bundles are compressed archives of ~/.tododata (todos, branches, commits,
+remotes and fetched remote states) with a checksum manifest. Stored login
+tokens and hooks are never included, and a restore keeps the local ones.
+
+A bundle path can also be used as a file remote, e.g.
+  todo remote add usb /media/usb/team.bundle -t file`,
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create <file>",
+	Short: "Write a bundle of all todo data",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Make sure a repository exists even on a fresh install
+		if _, err := storage_instance.LoadRepository(); err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		files, err := storage_instance.DataFiles()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		if err := bundle.Write(args[0], files); err != nil {
+			fmt.Printf("Error creating bundle: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Created bundle %s with %d files\n", args[0], len(files))
+	},
+}
+
+var bundleVerifyCmd = &cobra.Command{
+	Use:   "verify <file>",
+	Short: "Check a bundle against its checksum manifest",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		b, err := bundle.Read(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		if problems := b.Verify(); len(problems) > 0 {
+			fmt.Printf("Bundle %s is invalid:\n", args[0])
+			for _, problem := range problems {
+				fmt.Printf("  %v\n", problem)
+			}
+			return
+		}
+
+		repo, _ := b.Repository()
+		fmt.Printf("Bundle %s is valid (created %s)\n", args[0], b.Manifest.CreatedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("- %d files\n", len(b.Files))
+		fmt.Printf("- %d branches, %d commits, %d remotes\n", len(repo.Branches), len(repo.Commits), len(repo.Remotes))
+	},
+}
+
+var bundleRestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore todo data from a bundle, replacing or merging into the current data",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		merge, _ := cmd.Flags().GetBool("merge")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		b, err := bundle.Read(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if problems := b.Verify(); len(problems) > 0 {
+			fmt.Printf("Bundle %s is invalid, run 'todo bundle verify %s' for details\n", args[0], args[0])
+			return
+		}
+
+		bundleRepo, err := b.Repository()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		if merge {
+			repo, err := storage_instance.LoadRepository()
+			if err != nil {
+				fmt.Printf("Error loading repository: %v\n", err)
+				return
+			}
+
+			merged, report := remoteService.MergeRepositories(repo, bundleRepo)
+			err = storage_instance.SaveRepository(merged)
+			if err != nil {
+				fmt.Printf("Error saving repository: %v\n", err)
+				return
+			}
+
+			fmt.Printf("Merged bundle %s\n", args[0])
+			report.WriteText(os.Stdout, false)
+			return
+		}
+
+		if !yes {
+			fmt.Printf("Replace all todo data with %s (%d branches, %d commits)? (y/N): ", args[0], len(bundleRepo.Branches), len(bundleRepo.Commits))
+			var response string
+			fmt.Scanln(&response)
+			if response != "y" && response != "Y" {
+				fmt.Println("Restore cancelled")
+				return
+			}
+		}
+
+		restored, err := storage_instance.ReplaceDataFiles(b.Files)
+		if err != nil {
+			fmt.Printf("Error restoring bundle: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Restored %d files from %s\n", restored, args[0])
+		for _, r := range bundleRepo.Remotes {
+			if r.CredentialHelper != "" {
+				fmt.Printf("Cleared the credential helper of remote '%s', set it again with 'todo remote update %s --credential-helper'\n", r.Name, r.Name)
+			}
+		}
+	},
+}
+
+func init() {
+	bundleRestoreCmd.Flags().Bool("merge", false, "Merge the bundle into the current repository instead of replacing it")
+	bundleRestoreCmd.Flags().BoolP("yes", "y", false, "Replace without asking for confirmation")
+
+	BundleCmd.AddCommand(bundleCreateCmd)
+	BundleCmd.AddCommand(bundleVerifyCmd)
+	BundleCmd.AddCommand(bundleRestoreCmd)
+}
@@ -1,12 +1,11 @@
 package commands
 
 import (
-	"crypto/sha1"
 	"fmt"
 	"os/user"
 	"strings"
-	"time"
 	"todo-cli/models"
+	"todo-cli/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -34,42 +33,37 @@ var commitCreateCmd = &cobra.Command{
 			return
 		}
 
-		// Find completed todos
-		var completedTodos []int
-		for _, todo := range currentBranch.Todos {
-			if todo.Status == "completed" {
-				completedTodos = append(completedTodos, todo.ID)
+		snapshot := storage.CommitCandidates(repo, currentBranch)
+		if len(snapshot) == 0 {
+			fmt.Println("No completed todos changed since the last commit")
+			// Staged todos reopened or deleted since staging would otherwise
+			// stay staged, and hide every other completed todo from commits
+			if pruned := storage.PruneStaged(currentBranch); pruned > 0 {
+				if err := storage_instance.SaveRepository(repo); err != nil {
+					fmt.Printf("Error saving repository: %v\n", err)
+					return
+				}
+				fmt.Printf("Unstaged %d todos that are no longer completed\n", pruned)
 			}
-		}
-
-		if len(completedTodos) == 0 {
-			fmt.Println("No completed todos to commit")
 			return
 		}
 
-		// Get current user
-		currentUser, _ := user.Current()
-		author := currentUser.Username
-		if author == "" {
-			author = "unknown"
+		if noVerify, _ := cmd.Flags().GetBool("no-verify"); !noVerify {
+			err = runHook(hookPreCommit, map[string]interface{}{
+				"branch":  currentBranch.Name,
+				"message": message,
+				"todos":   snapshot,
+			})
+			if err != nil {
+				fmt.Printf("Commit aborted: %v\n", err)
+				return
+			}
 		}
 
-		// Generate commit ID
-		hash := sha1.New()
-		hash.Write([]byte(fmt.Sprintf("%s-%s-%d", message, currentBranch.Name, time.Now().UnixNano())))
-		commitID := fmt.Sprintf("%x", hash.Sum(nil))[:8]
-
-		// Create commit
-		commit := models.Commit{
-			ID:        commitID,
-			Message:   message,
-			Branch:    currentBranch.Name,
-			Todos:     completedTodos,
-			CreatedAt: time.Now(),
-			Author:    author,
-		}
+		commit := newCommit(repo, currentBranch.Name, message, snapshot)
 
 		repo.Commits = append(repo.Commits, commit)
+		currentBranch.Staged = nil
 
 		err = storage_instance.SaveRepository(repo)
 		if err != nil {
@@ -77,8 +71,13 @@ var commitCreateCmd = &cobra.Command{
 			return
 		}
 
-		fmt.Printf("Created commit %s: %s\n", commitID, message)
-		fmt.Printf("Committed %d completed todos\n", len(completedTodos))
+		fmt.Printf("Created commit %s: %s\n", commit.ID, message)
+		fmt.Printf("Committed %d completed todos\n", len(commit.Todos))
+
+		runPostHook(hookPostCommit, map[string]interface{}{
+			"branch": currentBranch.Name,
+			"commit": commit,
+		})
 	},
 }
 
@@ -120,15 +119,7 @@ var commitShowCmd = &cobra.Command{
 			return
 		}
 
-		// Find commit
-		var commit *models.Commit
-		for i := range repo.Commits {
-			if repo.Commits[i].ID == commitID {
-				commit = &repo.Commits[i]
-				break
-			}
-		}
-
+		commit := storage_instance.GetCommitByID(repo, commitID)
 		if commit == nil {
 			fmt.Printf("Commit %s not found\n", commitID)
 			return
@@ -141,23 +132,39 @@ var commitShowCmd = &cobra.Command{
 		fmt.Printf("Date: %s\n", commit.CreatedAt.Format("2006-01-02 15:04:05"))
 		fmt.Printf("Todos (%d):\n", len(commit.Todos))
 
-		// Find branch to get todo details
+		// Prefer the recorded snapshot, fall back to the branch for older commits
+		if len(commit.Snapshot) > 0 {
+			for _, todo := range commit.Snapshot {
+				fmt.Printf("  #%d %s - %s\n", todo.ID, todo.Title, todo.Description)
+			}
+			return
+		}
+
 		branch := storage_instance.GetBranchByName(repo, commit.Branch)
 		if branch != nil {
 			for _, todoID := range commit.Todos {
-				for _, todo := range branch.Todos {
-					if todo.ID == todoID {
-						fmt.Printf("  #%d %s - %s\n", todo.ID, todo.Title, todo.Description)
-						break
-					}
+				if todo := storage_instance.GetTodoByID(branch, todoID); todo != nil {
+					fmt.Printf("  #%d %s - %s\n", todo.ID, todo.Title, todo.Description)
 				}
 			}
 		}
 	},
 }
 
+// newCommit builds a commit by the current user for the given todo states
+func newCommit(repo *models.Repository, branchName, message string, snapshot []models.Todo) models.Commit {
+	author := "unknown"
+	if currentUser, err := user.Current(); err == nil && currentUser.Username != "" {
+		author = currentUser.Username
+	}
+
+	return storage.NewCommit(repo, branchName, message, author, snapshot)
+}
+
 func init() {
 	CommitCmd.AddCommand(commitCreateCmd)
 	CommitCmd.AddCommand(commitListCmd)
 	CommitCmd.AddCommand(commitShowCmd)
+
+	commitCreateCmd.Flags().Bool("no-verify", false, "Skip the pre-commit hook")
 }
@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"testing"
+	"todo-cli/models"
+)
+
+func TestCommitUnstagesTodosNoLongerCompleted(t *testing.T) {
+	useTempHome(t)
+	repo := hookTestRepository()
+	main := &repo.Branches[0]
+	main.Todos = append(main.Todos, models.Todo{ID: 3, Title: "Reopened", Status: "pending", Priority: "medium", BranchName: "main"})
+	// Todo 9 was deleted after being staged
+	main.Staged = []int{3, 9}
+	saveTestRepository(t, repo)
+
+	captureStdout(t, func() { commitCreateCmd.Run(commitCreateCmd, []string{"Nothing"}) })
+
+	saved := loadTestRepository(t)
+	if len(saved.Commits) != 0 || len(saved.Branches[0].Staged) != 0 {
+		t.Errorf("commits = %+v, staged = %v, want no commit and nothing staged", saved.Commits, saved.Branches[0].Staged)
+	}
+
+	captureStdout(t, func() { commitCreateCmd.Run(commitCreateCmd, []string{"Done"}) })
+	if commits := loadTestRepository(t).Commits; len(commits) != 1 || len(commits[0].Todos) != 1 || commits[0].Todos[0] != 1 {
+		t.Errorf("commits = %+v, want the completed todo committed", commits)
+	}
+}
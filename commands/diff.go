@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"todo-cli/diff"
+	"todo-cli/models"
+
+	"github.com/spf13/cobra"
+)
+
+var DiffCmd = &cobra.Command{
+	Use:   "diff [from] [to]",
+	Short: "Show todo changes between branches, commits or remote-tracking refs",
+	Long: `Show todo changes.
+
+Without arguments, shows completed todos changed since the last commit that are
+not staged. With --staged, shows the staged ones instead.
+
+With two arguments, compares two sides. Each side can be a branch name, a
+commit ID, or a remote-tracking ref such as origin/main (updated by fetch and pull).`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf("accepts 0 or 2 arg(s), received %d", len(args))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		staged, _ := cmd.Flags().GetBool("staged")
+		format, _ := cmd.Flags().GetString("format")
+
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		var result diff.Result
+		if len(args) == 2 {
+			fromTodos, err := resolveTodos(repo, args[0])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			toTodos, err := resolveTodos(repo, args[1])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			result = diff.Compare(args[0], fromTodos, args[1], toTodos)
+		} else {
+			currentBranch := storage_instance.GetCurrentBranch(repo)
+			if currentBranch == nil {
+				fmt.Println("No current branch found")
+				return
+			}
+
+			committed := storage_instance.GetCommittedTodos(repo, currentBranch.Name)
+
+			// Staged todos are shown with --staged, everything else without it
+			var before, after []models.Todo
+			for _, todo := range storage_instance.GetUncommittedTodos(repo, currentBranch.Name) {
+				if isStaged(currentBranch, todo.ID) != staged {
+					continue
+				}
+				after = append(after, todo)
+				if previous, ok := committed[todo.ID]; ok {
+					before = append(before, previous)
+				}
+			}
+
+			to := "working"
+			if staged {
+				to = "staged"
+			}
+			result = diff.Compare("HEAD", before, to, after)
+		}
+
+		printDiff(format, []diff.Result{result})
+	},
+}
+
+// resolveTodos returns the todos referenced by a branch name, commit ID or remote-tracking ref
+func resolveTodos(repo *models.Repository, ref string) ([]models.Todo, error) {
+	if branch := storage_instance.GetBranchByName(repo, ref); branch != nil {
+		return branch.Todos, nil
+	}
+
+	if storage_instance.GetCommitByID(repo, ref) != nil {
+		return storage_instance.GetTodosAtCommit(repo, ref)
+	}
+
+	if remoteName, branchName, ok := strings.Cut(ref, "/"); ok {
+		remoteRepo, err := storage_instance.LoadRemoteState(remoteName)
+		if err != nil {
+			return nil, err
+		}
+		if branch := storage_instance.GetBranchByName(remoteRepo, branchName); branch != nil {
+			return branch.Todos, nil
+		}
+		return nil, fmt.Errorf("branch '%s' not found on remote '%s'", branchName, remoteName)
+	}
+
+	return nil, fmt.Errorf("'%s' is not a branch, commit or remote-tracking ref", ref)
+}
+
+// printDiff writes diff results to stdout in the requested format
+func printDiff(format string, results []diff.Result) {
+	switch format {
+	case "json":
+		if err := diff.WriteJSON(os.Stdout, results); err != nil {
+			fmt.Printf("Error encoding diff: %v\n", err)
+		}
+	case "text", "":
+		if len(results) == 0 {
+			fmt.Println("No changes")
+			return
+		}
+		diff.WriteText(os.Stdout, results, isTerminal(os.Stdout))
+	default:
+		fmt.Printf("Unknown format '%s' (use text or json)\n", format)
+	}
+}
+
+// isTerminal reports whether the file is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func init() {
+	DiffCmd.Flags().Bool("staged", false, "Show changes staged for the next commit")
+	DiffCmd.Flags().StringP("format", "f", "text", "Output format (text, json)")
+}
@@ -0,0 +1,296 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"todo-cli/models"
+
+	"github.com/spf13/cobra"
+)
+
+var ExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export todos as markdown, CSV, iCalendar or todo.txt",
+	Long: `Export the todos of every branch, or of one branch with --branch.
+
+Formats:
+  markdown  a checklist and the commit history of each branch
+  csv       one row per todo, for spreadsheets
+  ics       an iCalendar file with a VTODO per todo, for calendar and task apps
+  todotxt   todo.txt lines that 'todo import --format todotxt' reads back`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		branchName, _ := cmd.Flags().GetString("branch")
+		status, _ := cmd.Flags().GetString("status")
+		output, _ := cmd.Flags().GetString("output")
+
+		if status != "" && !models.ValidStatus(status) {
+			fmt.Println("Status must be: pending, in-progress, or completed")
+			return
+		}
+
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		branches := repo.Branches
+		if branchName != "" {
+			branch := storage_instance.GetBranchByName(repo, branchName)
+			if branch == nil {
+				fmt.Printf("Branch '%s' does not exist\n", branchName)
+				return
+			}
+			branches = []models.Branch{*branch}
+		}
+
+		// Filter todos by status on copies of the branches
+		if status != "" {
+			filtered := make([]models.Branch, len(branches))
+			for i, branch := range branches {
+				branch.Todos = nil
+				for _, todo := range branches[i].Todos {
+					if todo.Status == status {
+						branch.Todos = append(branch.Todos, todo)
+					}
+				}
+				filtered[i] = branch
+			}
+			branches = filtered
+		}
+
+		var buf bytes.Buffer
+		switch format {
+		case "markdown":
+			writeMarkdownExport(&buf, repo, branches)
+		case "csv":
+			err = writeCSVExport(&buf, branches)
+		case "ics":
+			writeICSExport(&buf, branches)
+		case "todotxt":
+			writeTodoTxtExport(&buf, branches)
+		default:
+			fmt.Printf("Unknown format '%s' (use markdown, csv, ics or todotxt)\n", format)
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error exporting: %v\n", err)
+			return
+		}
+
+		if output == "" || output == "-" {
+			os.Stdout.Write(buf.Bytes())
+			return
+		}
+
+		if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+			fmt.Printf("Error writing %s: %v\n", output, err)
+			return
+		}
+
+		count := 0
+		for _, branch := range branches {
+			count += len(branch.Todos)
+		}
+		fmt.Printf("Exported %d todos from %d branches to %s\n", count, len(branches), output)
+	},
+}
+
+// writeMarkdownExport renders a checklist and commit history per branch
+func writeMarkdownExport(w io.Writer, repo *models.Repository, branches []models.Branch) {
+	fmt.Fprintln(w, "# Todos")
+
+	for _, branch := range branches {
+		fmt.Fprintln(w)
+		if branch.Name == repo.CurrentBranch {
+			fmt.Fprintf(w, "## %s (current)\n\n", branch.Name)
+		} else {
+			fmt.Fprintf(w, "## %s\n\n", branch.Name)
+		}
+
+		if len(branch.Todos) == 0 {
+			fmt.Fprintln(w, "_No todos_")
+		}
+		for _, todo := range branch.Todos {
+			check := " "
+			if todo.Status == "completed" {
+				check = "x"
+			}
+			details := todo.Priority
+			if todo.Status == "in-progress" {
+				details += ", in progress"
+			}
+			fmt.Fprintf(w, "- [%s] %s _(#%d, %s)_\n", check, todo.Title, todo.ID, details)
+			for _, line := range strings.Split(todo.Description, "\n") {
+				if strings.TrimSpace(line) != "" {
+					fmt.Fprintf(w, "  %s\n", line)
+				}
+			}
+		}
+
+		var commits []models.Commit
+		for _, commit := range repo.Commits {
+			if commit.Branch == branch.Name {
+				commits = append(commits, commit)
+			}
+		}
+		if len(commits) == 0 {
+			continue
+		}
+
+		fmt.Fprintln(w, "\n### Commits")
+		fmt.Fprintln(w)
+		for i := len(commits) - 1; i >= 0; i-- {
+			commit := commits[i]
+			fmt.Fprintf(w, "- `%s` %s (%s", commit.ID, commit.Message, commit.CreatedAt.Format("2006-01-02"))
+			if commit.Author != "" {
+				fmt.Fprintf(w, ", %s", commit.Author)
+			}
+			fmt.Fprintf(w, ", %d todos)\n", len(commit.Todos))
+		}
+	}
+}
+
+// writeCSVExport writes one row per todo with a header row
+func writeCSVExport(w io.Writer, branches []models.Branch) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "branch", "title", "description", "status", "priority", "created_at", "updated_at", "external_id"})
+
+	for _, branch := range branches {
+		for _, todo := range branch.Todos {
+			writer.Write([]string{
+				strconv.Itoa(todo.ID),
+				branch.Name,
+				todo.Title,
+				todo.Description,
+				todo.Status,
+				todo.Priority,
+				todo.CreatedAt.Format(time.RFC3339),
+				todo.UpdatedAt.Format(time.RFC3339),
+				todo.ExternalID,
+			})
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeICSExport writes an iCalendar file with a VTODO per todo. Todos have
+// no due dates, so calendar apps list them as undated tasks.
+func writeICSExport(w io.Writer, branches []models.Branch) {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//todo-cli//export//EN",
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	icsTime := func(t time.Time) string {
+		return t.UTC().Format("20060102T150405Z")
+	}
+
+	for _, branch := range branches {
+		for _, todo := range branch.Todos {
+			lines = append(lines,
+				"BEGIN:VTODO",
+				fmt.Sprintf("UID:todo-%d-%s@todo-cli", todo.ID, icsEscape(branch.Name)),
+				"DTSTAMP:"+stamp,
+				"CREATED:"+icsTime(todo.CreatedAt),
+				"LAST-MODIFIED:"+icsTime(todo.UpdatedAt),
+				"SUMMARY:"+icsEscape(todo.Title),
+				"CATEGORIES:"+icsEscape(branch.Name),
+				"PRIORITY:"+map[string]string{"high": "1", "medium": "5", "low": "9"}[todo.Priority],
+			)
+			if todo.Description != "" {
+				lines = append(lines, "DESCRIPTION:"+icsEscape(todo.Description))
+			}
+			switch todo.Status {
+			case "completed":
+				lines = append(lines, "STATUS:COMPLETED", "COMPLETED:"+icsTime(todo.UpdatedAt), "PERCENT-COMPLETE:100")
+			case "in-progress":
+				lines = append(lines, "STATUS:IN-PROCESS")
+			default:
+				lines = append(lines, "STATUS:NEEDS-ACTION")
+			}
+			lines = append(lines, "END:VTODO")
+		}
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		io.WriteString(w, icsFold(line)+"\r\n")
+	}
+}
+
+// icsEscape escapes text values as required by RFC 5545
+func icsEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// icsFold splits lines longer than 75 octets into continuation lines,
+// without breaking UTF-8 sequences
+func icsFold(line string) string {
+	var folded strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			folded.WriteString("\r\n ")
+			width = 1
+		}
+		folded.WriteRune(r)
+		width += size
+	}
+	return folded.String()
+}
+
+// writeTodoTxtExport writes todo.txt lines: priority (A) high, (B) medium,
+// (C) low, and completed todos as "x <done> <created> title pri:X". The
+// ext:, desc: and status: tags carry what todo.txt has no syntax for, so
+// 'todo import' recognizes the todos again and restores them.
+func writeTodoTxtExport(w io.Writer, branches []models.Branch) {
+	priorities := map[string]string{"high": "A", "medium": "B", "low": "C"}
+
+	for _, branch := range branches {
+		for _, todo := range branch.Todos {
+			title := strings.Join(strings.Fields(todo.Title), " ")
+			priority := priorities[todo.Priority]
+			created := todo.CreatedAt.Format("2006-01-02")
+
+			externalID := todo.ExternalID
+			if externalID == "" {
+				externalID = "todo:" + strconv.Itoa(todo.ID)
+			}
+			tags := "ext:" + url.PathEscape(externalID)
+			if todo.Description != "" {
+				tags += " desc:" + url.PathEscape(todo.Description)
+			}
+			if todo.Status == "in-progress" {
+				tags += " status:in-progress"
+			}
+
+			if todo.Status == "completed" {
+				fmt.Fprintf(w, "x %s %s %s pri:%s %s\n", todo.UpdatedAt.Format("2006-01-02"), created, title, priority, tags)
+			} else {
+				fmt.Fprintf(w, "(%s) %s %s %s\n", priority, created, title, tags)
+			}
+		}
+	}
+}
+
+func init() {
+	ExportCmd.Flags().StringP("format", "f", "markdown", "Output format: markdown, csv, ics or todotxt")
+	ExportCmd.Flags().StringP("branch", "b", "", "Export only this branch (default: all branches)")
+	ExportCmd.Flags().StringP("status", "s", "", "Export only todos with this status")
+	ExportCmd.Flags().StringP("output", "o", "", "Write to a file instead of stdout")
+}
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"todo-cli/models"
+)
+
+func TestTodoTxtExportRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	todos := []models.Todo{
+		{ID: 1, Title: "Write  docs", Description: "Cover install\nand usage: 100%", Status: "in-progress", Priority: "high", CreatedAt: created},
+		{ID: 2, Title: "Ship it", Status: "completed", Priority: "low", CreatedAt: created, UpdatedAt: created},
+		{ID: 3, Title: "Imported", Status: "pending", Priority: "medium", CreatedAt: created, ExternalID: "csv:PROJ 7"},
+	}
+
+	var buf bytes.Buffer
+	writeTodoTxtExport(&buf, []models.Branch{{Name: "main", Todos: todos}})
+
+	items, err := parseTodoTxt(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []importedTodo{
+		{ExternalID: "todo:1", Title: "Write docs", Description: "Cover install\nand usage: 100%", Status: "in-progress", Priority: "high"},
+		{ExternalID: "todo:2", Title: "Ship it", Status: "completed", Priority: "low"},
+		{ExternalID: "csv:PROJ 7", Title: "Imported", Status: "pending", Priority: "medium"},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("items = %+v", items)
+	}
+	for i := range want {
+		if items[i] != want[i] {
+			t.Errorf("item %d = %+v, want %+v", i, items[i], want[i])
+		}
+	}
+}
+
+func TestTodoTxtImportSkipsExportedTodos(t *testing.T) {
+	useTempHome(t)
+	saveTestRepository(t, &models.Repository{
+		Branches:      []models.Branch{{Name: "main", IsActive: true}},
+		CurrentBranch: "main",
+		NextTodoID:    1,
+	})
+
+	file := filepath.Join(t.TempDir(), "todo.txt")
+	var buf bytes.Buffer
+	writeTodoTxtExport(&buf, []models.Branch{{Name: "main", Todos: []models.Todo{{ID: 4, Title: "Renamed later", Status: "pending", Priority: "medium"}}}})
+	writeFile(t, file, buf.String())
+
+	ImportCmd.Run(ImportCmd, []string{file})
+	// Edited titles keep their identity through the ext: tag
+	writeFile(t, file, strings.ReplaceAll(buf.String(), "Renamed later", "New title"))
+	ImportCmd.Run(ImportCmd, []string{file})
+
+	todos := loadTestRepository(t).Branches[0].Todos
+	if len(todos) != 1 || todos[0].ExternalID != "todo:4" {
+		t.Errorf("todos = %+v, want one todo imported once", todos)
+	}
+}
+
+func TestTodoTxtExportImportIntoSameRepository(t *testing.T) {
+	useTempHome(t)
+	saveTestRepository(t, &models.Repository{
+		Branches: []models.Branch{{
+			Name:     "main",
+			IsActive: true,
+			Todos: []models.Todo{
+				{ID: 1, Title: "Native", Status: "pending", Priority: "high", BranchName: "main"},
+				{ID: 2, Title: "Done", Status: "completed", Priority: "low", BranchName: "main"},
+				{ID: 3, Title: "From a tracker", Status: "pending", Priority: "medium", BranchName: "main", ExternalID: "csv:PROJ-7"},
+			},
+		}},
+		CurrentBranch: "main",
+		NextTodoID:    4,
+	})
+
+	file := filepath.Join(t.TempDir(), "todo.txt")
+	setFlags(t, ExportCmd, map[string]string{"format": "todotxt", "output": file})
+	captureStdout(t, func() { ExportCmd.Run(ExportCmd, nil) })
+	captureStdout(t, func() { ImportCmd.Run(ImportCmd, []string{file}) })
+
+	if todos := loadTestRepository(t).Branches[0].Todos; len(todos) != 3 {
+		t.Errorf("todos = %+v, want the three original todos only", todos)
+	}
+}
@@ -0,0 +1,247 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"todo-cli/remote"
+
+	"github.com/spf13/cobra"
+)
+
+const gitHookMarker = "Installed by 'todo git install-hook'"
+
+// gitTodoReference matches todo references in git commit messages, such as
+// "todo #12", "closes #12" or "fixes #12"
+var gitTodoReference = regexp.MustCompile(`(?i)\b(?:todo|close[sd]?|fix(?:e[sd])?|resolve[sd]?)\s+#(\d+)\b`)
+
+var GitCmd = &cobra.Command{
+	Use:   "git",
+	Short: "Integrate with the git repository in the current directory",
+}
+
+var gitLinkCmd = &cobra.Command{
+	Use:   "link [revision-range]",
+	Short: "Complete todos referenced by git commits",
+	Long: `Scan git log (default: HEAD) for "todo #N", "closes #N", "fixes #N" or
+"resolves #N" and complete the referenced todos on the current branch. The git
+commits are recorded on each todo, so running link again is harmless.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		revisions := "HEAD"
+		if len(args) > 0 {
+			revisions = args[0]
+		}
+		// git would read a leading - as an option such as --output
+		if strings.HasPrefix(revisions, "-") {
+			fmt.Printf("Error: invalid revision range '%s'\n", revisions)
+			return
+		}
+
+		// Commits are separated by \x1e and hash from message by \x1f
+		output, err := runGit("log", "--format=%H%x1f%B%x1e", revisions)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		currentBranch := storage_instance.GetCurrentBranch(repo)
+		if currentBranch == nil {
+			fmt.Println("No current branch found")
+			return
+		}
+
+		linked, completed := 0, 0
+		// git log lists newest first; link oldest first so todos record commits in order
+		entries := strings.Split(output, "\x1e")
+		for i := len(entries) - 1; i >= 0; i-- {
+			hash, message, ok := strings.Cut(strings.TrimSpace(entries[i]), "\x1f")
+			if !ok {
+				continue
+			}
+			shortHash := hash[:min(len(hash), 12)]
+			subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+
+			for _, match := range gitTodoReference.FindAllStringSubmatch(message, -1) {
+				id, _ := strconv.Atoi(match[1])
+				todo := storage_instance.GetTodoByID(currentBranch, id)
+				if todo == nil {
+					fmt.Printf("Warning: %s references todo #%d, which is not on branch '%s'\n", shortHash, id, currentBranch.Name)
+					continue
+				}
+
+				known := false
+				for _, commit := range todo.GitCommits {
+					known = known || commit == shortHash
+				}
+				if known {
+					continue
+				}
+
+				todo.GitCommits = append(todo.GitCommits, shortHash)
+				linked++
+				if todo.Status != "completed" {
+					todo.Status = "completed"
+					todo.UpdatedAt = time.Now()
+					completed++
+					fmt.Printf("Completed todo #%d: %s (%s %s)\n", todo.ID, todo.Title, shortHash[:7], subject)
+				} else {
+					fmt.Printf("Linked todo #%d: %s (%s %s)\n", todo.ID, todo.Title, shortHash[:7], subject)
+				}
+			}
+		}
+
+		if linked == 0 {
+			fmt.Println("No new todo references found")
+			return
+		}
+
+		if dryRun {
+			fmt.Printf("Dry run: would link %d references and complete %d todos\n", linked, completed)
+			return
+		}
+
+		err = storage_instance.SaveRepository(repo)
+		if err != nil {
+			fmt.Printf("Error saving repository: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Linked %d references, completed %d todos\n", linked, completed)
+	},
+}
+
+var gitInstallHookCmd = &cobra.Command{
+	Use:   "install-hook",
+	Short: "Install a git prepare-commit-msg hook listing open todos",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		force, _ := cmd.Flags().GetBool("force")
+
+		hooksDir, err := runGit("rev-parse", "--git-path", "hooks")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		hookPath := filepath.Join(hooksDir, "prepare-commit-msg")
+
+		if existing, err := os.ReadFile(hookPath); err == nil && !force && !strings.Contains(string(existing), gitHookMarker) {
+			fmt.Printf("%s already exists, use --force to replace it\n", hookPath)
+			return
+		}
+
+		executable, err := os.Executable()
+		if err != nil {
+			fmt.Printf("Error locating todo executable: %v\n", err)
+			return
+		}
+
+		script := gitHookScript(executable)
+		if err := os.MkdirAll(hooksDir, 0755); err != nil {
+			fmt.Printf("Error creating hooks directory: %v\n", err)
+			return
+		}
+		if err := os.WriteFile(hookPath, []byte(script), 0755); err != nil {
+			fmt.Printf("Error writing hook: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Installed %s\n", hookPath)
+	},
+}
+
+// gitPrepareCommitMsgCmd is run by the installed git hook
+var gitPrepareCommitMsgCmd = &cobra.Command{
+	Use:    "prepare-commit-msg <file> [source] [commit]",
+	Short:  "Add open todos to a git commit message template",
+	Hidden: true,
+	Args:   cobra.RangeArgs(1, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Only edit templates git opens in an editor: comment lines survive -m messages,
+		// and merges, squashes and amends already have a message
+		if len(args) > 1 && args[1] != "template" {
+			return
+		}
+
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			return
+		}
+		currentBranch := storage_instance.GetCurrentBranch(repo)
+		if currentBranch == nil {
+			return
+		}
+
+		var lines bytes.Buffer
+		for _, todo := range currentBranch.Todos {
+			if todo.Status != "completed" {
+				fmt.Fprintf(&lines, "#   todo #%d %s (%s)\n", todo.ID, todo.Title, todo.Status)
+			}
+		}
+		if lines.Len() == 0 {
+			return
+		}
+
+		file, err := os.OpenFile(args[0], os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "todo: %v\n", err)
+			return
+		}
+		defer file.Close()
+
+		fmt.Fprintf(file, "#\n# Open todos on branch '%s' (reference with \"todo #N\" or \"closes #N\"):\n", currentBranch.Name)
+		file.Write(lines.Bytes())
+	},
+}
+
+// gitHookScript returns the prepare-commit-msg hook running executable
+func gitHookScript(executable string) string {
+	return fmt.Sprintf("#!/bin/sh\n# %s\nexec %s git prepare-commit-msg \"$@\"\n", gitHookMarker, remote.ShellQuote(executable))
+}
+
+// runGit runs the local git binary and returns its trimmed output
+func runGit(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// currentGitBranch returns the branch checked out in the current git repository
+func currentGitBranch() (string, error) {
+	branch, err := runGit("symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("not on a git branch: %w", err)
+	}
+	return branch, nil
+}
+
+func init() {
+	gitLinkCmd.Flags().Bool("dry-run", false, "Show which todos would be completed without saving")
+	gitInstallHookCmd.Flags().BoolP("force", "f", false, "Replace an existing prepare-commit-msg hook")
+
+	GitCmd.AddCommand(gitLinkCmd)
+	GitCmd.AddCommand(gitInstallHookCmd)
+	GitCmd.AddCommand(gitPrepareCommitMsgCmd)
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"todo-cli/models"
+)
+
+// gitTestRepository returns a repository with two pending todos on main
+func gitTestRepository() *models.Repository {
+	return &models.Repository{
+		Branches: []models.Branch{{
+			Name:     "main",
+			IsActive: true,
+			Todos: []models.Todo{
+				{ID: 1, Title: "Write docs", Status: "pending", BranchName: "main"},
+				{ID: 2, Title: "Fix build", Status: "pending", BranchName: "main"},
+			},
+		}},
+		CurrentBranch: "main",
+		NextTodoID:    3,
+	}
+}
+
+func TestGitLinkCompletesReferencedTodos(t *testing.T) {
+	requireGit(t)
+	useTempHome(t)
+	saveTestRepository(t, gitTestRepository())
+
+	dir := t.TempDir()
+	git(t, dir, "init", "-q")
+	git(t, dir, "commit", "-q", "--allow-empty", "-m", "Add guide\n\ncloses #1")
+	git(t, dir, "commit", "-q", "--allow-empty", "-m", "Unrelated change")
+	chdir(t, dir)
+
+	gitLinkCmd.Run(gitLinkCmd, nil)
+
+	branch := loadTestRepository(t).Branches[0]
+	if todo := branch.Todos[0]; todo.Status != "completed" || len(todo.GitCommits) != 1 {
+		t.Errorf("todo #1 = %+v, want completed with one git commit", todo)
+	}
+	if todo := branch.Todos[1]; todo.Status != "pending" || len(todo.GitCommits) != 0 {
+		t.Errorf("todo #2 = %+v, want untouched", todo)
+	}
+
+	// Linking again records nothing new
+	gitLinkCmd.Run(gitLinkCmd, nil)
+	if commits := loadTestRepository(t).Branches[0].Todos[0].GitCommits; len(commits) != 1 {
+		t.Errorf("git commits after second link = %v", commits)
+	}
+}
+
+func TestGitLinkRejectsOptionRevisions(t *testing.T) {
+	requireGit(t)
+	useTempHome(t)
+	saveTestRepository(t, gitTestRepository())
+
+	dir := t.TempDir()
+	git(t, dir, "init", "-q")
+	git(t, dir, "commit", "-q", "--allow-empty", "-m", "closes #1")
+	chdir(t, dir)
+
+	written := filepath.Join(t.TempDir(), "log")
+	gitLinkCmd.Run(gitLinkCmd, []string{"--output=" + written})
+
+	if _, err := os.Stat(written); err == nil {
+		t.Error("revision was passed to git as an option")
+	}
+	if todo := loadTestRepository(t).Branches[0].Todos[0]; todo.Status != "pending" {
+		t.Errorf("todo #1 = %+v, want pending", todo)
+	}
+}
+
+func TestGitHookScriptQuotesExecutable(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+
+	// A fake todo executable in a directory whose name the shell would expand
+	dir := filepath.Join(t.TempDir(), "it's $HOME `id` \\ \"todo\"")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	received := filepath.Join(t.TempDir(), "args")
+	executable := filepath.Join(dir, "todo")
+	fake := "#!/bin/sh\nprintf '%s\\n' \"$@\" > '" + received + "'\n"
+	if err := os.WriteFile(executable, []byte(fake), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	hook := filepath.Join(t.TempDir(), "prepare-commit-msg")
+	if err := os.WriteFile(hook, []byte(gitHookScript(executable)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := exec.Command("sh", hook, "COMMIT_EDITMSG", "template").CombinedOutput(); err != nil {
+		t.Fatalf("hook failed: %v\n%s", err, output)
+	}
+
+	args, err := os.ReadFile(received)
+	if err != nil {
+		t.Fatalf("fake executable was not run: %v", err)
+	}
+	if got, want := strings.TrimSpace(string(args)), "git\nprepare-commit-msg\nCOMMIT_EDITMSG\ntemplate"; got != want {
+		t.Errorf("arguments = %q, want %q", got, want)
+	}
+}
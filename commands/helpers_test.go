@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+	"todo-cli/models"
+	"todo-cli/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// useTempHome points the commands at an empty data directory for one test
+func useTempHome(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	previous := storage_instance
+	storage_instance = storage.NewStorage()
+	t.Cleanup(func() { storage_instance = previous })
+}
+
+// saveTestRepository stores repo as the local repository
+func saveTestRepository(t *testing.T, repo *models.Repository) {
+	t.Helper()
+	if err := storage_instance.SaveRepository(repo); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// loadTestRepository reads the local repository
+func loadTestRepository(t *testing.T) *models.Repository {
+	t.Helper()
+	repo, err := storage_instance.LoadRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
+
+// requireGit skips tests that need the git binary when it is missing
+func requireGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+}
+
+// git runs git in dir with a fixed identity and fails the test on errors
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+	return string(output)
+}
+
+// writeFile writes data to path
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setFlags sets flags of cmd for the rest of the test
+func setFlags(t *testing.T, cmd *cobra.Command, values map[string]string) {
+	t.Helper()
+	for name, value := range values {
+		if err := cmd.Flags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		for name := range values {
+			flag := cmd.Flags().Lookup(name)
+			flag.Value.Set(flag.DefValue)
+			flag.Changed = false
+		}
+	})
+}
+
+// captureStdout returns what run writes to stdout
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	run()
+	os.Stdout = previous
+	w.Close()
+	return string(<-done)
+}
@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+	"todo-cli/models"
+
+	"github.com/spf13/cobra"
+)
+
+var RevertCmd = &cobra.Command{
+	Use:   "revert [commit_id]",
+	Short: "Restore the todos of a commit to their pre-commit state in a new commit",
+	Long: `Restore the todos included in a commit to the state they had before it.
+
+Todos that the commit recorded for the first time are reopened as pending.
+The restored states are recorded in a new commit on the current branch.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		currentBranch := storage_instance.GetCurrentBranch(repo)
+		if currentBranch == nil {
+			fmt.Println("No current branch found")
+			return
+		}
+
+		commit := storage_instance.GetCommitByID(repo, args[0])
+		if commit == nil {
+			fmt.Printf("Commit %s not found\n", args[0])
+			return
+		}
+		if len(commit.Snapshot) == 0 {
+			fmt.Printf("Commit %s has no recorded todo states and cannot be reverted\n", commit.ID)
+			return
+		}
+
+		before := make(map[int]models.Todo)
+		for _, todo := range commit.Before {
+			before[todo.ID] = todo
+		}
+
+		var snapshot []models.Todo
+		for _, committed := range commit.Snapshot {
+			todo := storage_instance.GetTodoByID(currentBranch, committed.ID)
+			if todo == nil {
+				fmt.Printf("Skipping todo #%d: not found in current branch\n", committed.ID)
+				continue
+			}
+
+			if previous, ok := before[committed.ID]; ok {
+				applyTodoState(todo, previous)
+			} else {
+				reopened := committed
+				reopened.Status = "pending"
+				applyTodoState(todo, reopened)
+			}
+			snapshot = append(snapshot, *todo)
+		}
+
+		if len(snapshot) == 0 {
+			fmt.Println("Nothing to revert")
+			return
+		}
+
+		message := fmt.Sprintf("Revert \"%s\"", commit.Message)
+		revertCommit := newCommit(repo, currentBranch.Name, message, snapshot)
+		repo.Commits = append(repo.Commits, revertCommit)
+
+		err = storage_instance.SaveRepository(repo)
+		if err != nil {
+			fmt.Printf("Error saving repository: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Created commit %s: %s\n", revertCommit.ID, message)
+		fmt.Printf("Reverted %d todos\n", len(snapshot))
+	},
+}
+
+var CherryPickCmd = &cobra.Command{
+	Use:   "cherry-pick [commit_id]",
+	Short: "Apply the todo changes of a commit onto the current branch",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		currentBranch := storage_instance.GetCurrentBranch(repo)
+		if currentBranch == nil {
+			fmt.Println("No current branch found")
+			return
+		}
+
+		commit := storage_instance.GetCommitByID(repo, args[0])
+		if commit == nil {
+			fmt.Printf("Commit %s not found\n", args[0])
+			return
+		}
+		if len(commit.Snapshot) == 0 {
+			fmt.Printf("Commit %s has no recorded todo states and cannot be cherry-picked\n", commit.ID)
+			return
+		}
+		if commit.Branch == currentBranch.Name {
+			fmt.Printf("Commit %s is already on branch '%s'\n", commit.ID, currentBranch.Name)
+			return
+		}
+
+		var snapshot []models.Todo
+		for _, committed := range commit.Snapshot {
+			if todo := storage_instance.GetTodoByID(currentBranch, committed.ID); todo != nil {
+				applyTodoState(todo, committed)
+				snapshot = append(snapshot, *todo)
+				continue
+			}
+
+			// Copy todos that do not exist on this branch yet
+			picked := committed
+			picked.BranchName = currentBranch.Name
+			picked.UpdatedAt = time.Now()
+			currentBranch.Todos = append(currentBranch.Todos, picked)
+			snapshot = append(snapshot, picked)
+		}
+
+		message := fmt.Sprintf("%s (cherry picked from %s)", commit.Message, commit.ID)
+		pickedCommit := newCommit(repo, currentBranch.Name, message, snapshot)
+		repo.Commits = append(repo.Commits, pickedCommit)
+
+		err = storage_instance.SaveRepository(repo)
+		if err != nil {
+			fmt.Printf("Error saving repository: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Created commit %s: %s\n", pickedCommit.ID, message)
+		fmt.Printf("Applied %d todos\n", len(snapshot))
+	},
+}
+
+var ResetCmd = &cobra.Command{
+	Use:   "reset --to [commit_id]",
+	Short: "Roll the current branch back to a commit",
+	Long: `Remove every commit made on the current branch after the given commit.
+
+By default todos are left untouched, so their changes show up as uncommitted
+again. With --hard, todos are also restored to their state at that commit, and
+todos first committed later are reopened as pending.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		target, _ := cmd.Flags().GetString("to")
+		hard, _ := cmd.Flags().GetBool("hard")
+
+		if target == "" {
+			fmt.Println("A target commit is required (--to <commit_id>)")
+			return
+		}
+
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		currentBranch := storage_instance.GetCurrentBranch(repo)
+		if currentBranch == nil {
+			fmt.Println("No current branch found")
+			return
+		}
+
+		commit := storage_instance.GetCommitByID(repo, target)
+		if commit == nil {
+			fmt.Printf("Commit %s not found\n", target)
+			return
+		}
+		if commit.Branch != currentBranch.Name {
+			fmt.Printf("Commit %s is not on branch '%s'\n", commit.ID, currentBranch.Name)
+			return
+		}
+
+		committed := make(map[int]models.Todo)
+		if hard {
+			todos, err := storage_instance.GetTodosAtCommit(repo, commit.ID)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			for _, todo := range todos {
+				committed[todo.ID] = todo
+			}
+		}
+
+		// Drop every commit on this branch after the target
+		var kept []models.Commit
+		var dropped []models.Commit
+		found := false
+		for _, c := range repo.Commits {
+			if c.Branch == currentBranch.Name && found {
+				dropped = append(dropped, c)
+				continue
+			}
+			if c.ID == commit.ID {
+				found = true
+			}
+			kept = append(kept, c)
+		}
+
+		if hard {
+			for _, c := range dropped {
+				for _, id := range c.Todos {
+					todo := storage_instance.GetTodoByID(currentBranch, id)
+					if todo == nil {
+						continue
+					}
+					if state, ok := committed[id]; ok {
+						applyTodoState(todo, state)
+					} else if todo.Status == "completed" {
+						reopened := *todo
+						reopened.Status = "pending"
+						applyTodoState(todo, reopened)
+					}
+				}
+			}
+		}
+
+		repo.Commits = kept
+		currentBranch.Staged = nil
+
+		err = storage_instance.SaveRepository(repo)
+		if err != nil {
+			fmt.Printf("Error saving repository: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Reset branch '%s' to %s: %s\n", currentBranch.Name, commit.ID, commit.Message)
+		fmt.Printf("Removed %d commits\n", len(dropped))
+	},
+}
+
+// applyTodoState copies the user-visible fields of state onto todo
+func applyTodoState(todo *models.Todo, state models.Todo) {
+	todo.Title = state.Title
+	todo.Description = state.Description
+	todo.Status = state.Status
+	todo.Priority = state.Priority
+	todo.UpdatedAt = time.Now()
+}
+
+func init() {
+	ResetCmd.Flags().String("to", "", "Commit to reset the current branch to")
+	ResetCmd.Flags().Bool("hard", false, "Also restore todos to their state at the commit")
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// Client-side hooks, executables in ~/.tododata/hooks named after the hook
+const (
+	hookPreCommit  = "pre-commit"
+	hookPostCommit = "post-commit"
+	hookPrePush    = "pre-push"
+	hookPreMerge   = "pre-merge"
+	hookPostMerge  = "post-merge"
+	hookPreSwitch  = "pre-switch"
+	hookPostSwitch = "post-switch"
+)
+
+// runHook runs a hook if it is installed, passing payload as JSON on stdin.
+// Hooks that are missing or not executable are skipped. For pre-hooks the
+// returned error aborts the operation; post-hook errors are only reported.
+func runHook(name string, payload map[string]interface{}) error {
+	return runHookTo(name, payload, os.Stdout)
+}
+
+// runHookTo runs a hook like runHook, sending its standard output to stdout
+func runHookTo(name string, payload map[string]interface{}, stdout io.Writer) error {
+	path := storage_instance.HookPath(name)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+		return nil
+	}
+
+	payload["hook"] = name
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s hook input: %w", name, err)
+	}
+
+	cmd := exec.Command(path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "TODO_HOOK="+name)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook failed: %w", name, err)
+	}
+
+	return nil
+}
+
+// runPostHook runs a hook whose failure cannot undo the operation
+func runPostHook(name string, payload map[string]interface{}) {
+	if err := runHook(name, payload); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
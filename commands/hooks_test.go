@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"todo-cli/models"
+)
+
+// hookTestRepository returns a repository with a completed todo on main and
+// a feature branch adding another todo
+func hookTestRepository() *models.Repository {
+	return &models.Repository{
+		Branches: []models.Branch{
+			{
+				Name:     "main",
+				IsActive: true,
+				Todos:    []models.Todo{{ID: 1, Title: "Done", Status: "completed", Priority: "medium", BranchName: "main"}},
+			},
+			{
+				Name:   "feature",
+				Parent: "main",
+				Todos:  []models.Todo{{ID: 2, Title: "New", Status: "pending", Priority: "medium", BranchName: "feature"}},
+			},
+		},
+		CurrentBranch: "main",
+		NextTodoID:    3,
+	}
+}
+
+// recordingHook installs a hook that saves its input and exits with status,
+// and returns the file the input is saved to
+func recordingHook(t *testing.T, name, status string) string {
+	t.Helper()
+	input := filepath.Join(t.TempDir(), name+".json")
+	installHook(t, name, "#!/bin/sh\ncat > '"+input+"'\nexit "+status+"\n")
+	return input
+}
+
+// hookInput returns the JSON input a hook received, or nil if it did not run
+func hookInput(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	var input map[string]interface{}
+	if err := json.Unmarshal(data, &input); err != nil {
+		t.Fatalf("hook input %q: %v", data, err)
+	}
+	return input
+}
+
+func TestPreCommitHookAborts(t *testing.T) {
+	useTempHome(t)
+	saveTestRepository(t, hookTestRepository())
+	pre := recordingHook(t, hookPreCommit, "1")
+	post := recordingHook(t, hookPostCommit, "0")
+
+	captureStdout(t, func() { commitCreateCmd.Run(commitCreateCmd, []string{"Finish", "work"}) })
+
+	input := hookInput(t, pre)
+	if input == nil || input["hook"] != hookPreCommit || input["branch"] != "main" || input["message"] != "Finish work" {
+		t.Errorf("pre-commit input = %v", input)
+	}
+	if commits := loadTestRepository(t).Commits; len(commits) != 0 {
+		t.Errorf("commits = %+v, want the commit aborted", commits)
+	}
+	if hookInput(t, post) != nil {
+		t.Error("post-commit ran for an aborted commit")
+	}
+
+	setFlags(t, commitCreateCmd, map[string]string{"no-verify": "true"})
+	captureStdout(t, func() { commitCreateCmd.Run(commitCreateCmd, []string{"Finish"}) })
+	if commits := loadTestRepository(t).Commits; len(commits) != 1 {
+		t.Errorf("commits with --no-verify = %+v, want one", commits)
+	}
+	if input := hookInput(t, post); input == nil || input["branch"] != "main" {
+		t.Errorf("post-commit input = %v", input)
+	}
+}
+
+func TestPreMergeHookAborts(t *testing.T) {
+	useTempHome(t)
+	saveTestRepository(t, hookTestRepository())
+	pre := recordingHook(t, hookPreMerge, "1")
+
+	captureStdout(t, func() { MergeCmd.Run(MergeCmd, []string{"feature"}) })
+
+	input := hookInput(t, pre)
+	if input == nil || input["source"] != "feature" || input["target"] != "main" || input["added"] != float64(1) {
+		t.Errorf("pre-merge input = %v", input)
+	}
+	if todos := loadTestRepository(t).Branches[0].Todos; len(todos) != 1 {
+		t.Errorf("main todos = %+v, want the merge aborted", todos)
+	}
+
+	setFlags(t, MergeCmd, map[string]string{"no-verify": "true"})
+	captureStdout(t, func() { MergeCmd.Run(MergeCmd, []string{"feature"}) })
+	if todos := loadTestRepository(t).Branches[0].Todos; len(todos) != 2 {
+		t.Errorf("main todos with --no-verify = %+v, want the feature todo merged", todos)
+	}
+}
+
+func TestPreSwitchHookAborts(t *testing.T) {
+	useTempHome(t)
+	saveTestRepository(t, hookTestRepository())
+	pre := recordingHook(t, hookPreSwitch, "1")
+	post := recordingHook(t, hookPostSwitch, "0")
+
+	captureStdout(t, func() { branchSwitchCmd.Run(branchSwitchCmd, []string{"feature"}) })
+
+	if input := hookInput(t, pre); input == nil || input["previous"] != "main" || input["branch"] != "feature" {
+		t.Errorf("pre-switch input = %v", input)
+	}
+	if current := loadTestRepository(t).CurrentBranch; current != "main" {
+		t.Errorf("current branch = %s, want the switch aborted", current)
+	}
+	if hookInput(t, post) != nil {
+		t.Error("post-switch ran for an aborted switch")
+	}
+
+	setFlags(t, branchSwitchCmd, map[string]string{"no-verify": "true"})
+	captureStdout(t, func() { branchSwitchCmd.Run(branchSwitchCmd, []string{"feature"}) })
+	if current := loadTestRepository(t).CurrentBranch; current != "feature" {
+		t.Errorf("current branch with --no-verify = %s, want feature", current)
+	}
+	if input := hookInput(t, post); input == nil || input["previous"] != "main" || input["branch"] != "feature" {
+		t.Errorf("post-switch input = %v", input)
+	}
+}
@@ -0,0 +1,519 @@
+package commands
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"todo-cli/models"
+
+	"github.com/spf13/cobra"
+)
+
+// importedTodo is a todo read from an external file, before it gets an ID
+type importedTodo struct {
+	ExternalID  string
+	Title       string
+	Description string
+	Status      string
+	Priority    string
+}
+
+// Default CSV columns and JSON keys for each todo field, matched case-insensitively
+var importFieldNames = map[string][]string{
+	"id":          {"id", "iid", "number", "key", "issue key", "issue id", "url", "html_url", "web_url"},
+	"title":       {"title", "summary", "name", "subject"},
+	"description": {"description", "body", "details"},
+	"status":      {"status", "state"},
+	"priority":    {"priority", "severity", "labels"},
+}
+
+var ImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import todos from markdown checklists, CSV, JSON issue exports or todo.txt",
+	Long: `Import todos into the current branch. Each imported todo keeps a stable
+external ID, so importing the same file again skips todos that already exist.
+
+Formats:
+  markdown     "- [ ] task" and "- [x] task" checklist items; indented lines become the description
+  csv          a header row; columns are matched by name (title, description, status, priority, id)
+  json-issues  an array of GitHub or GitLab issues (or an object with an "issues" array)
+  todotxt      todo.txt lines; "x " marks done and (A)/(B)/(C) set the priority
+
+Use --map field=column (e.g. --map title=Summary) to pick CSV columns or JSON keys.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		mappings, _ := cmd.Flags().GetStringSlice("map")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if format == "" {
+			format = importFormatFromPath(args[0])
+		}
+
+		fieldNames, err := importFieldMapping(mappings)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			fmt.Printf("Error opening file: %v\n", err)
+			return
+		}
+		defer file.Close()
+
+		var items []importedTodo
+		switch format {
+		case "markdown":
+			items, err = parseMarkdownChecklist(file)
+		case "csv":
+			items, err = parseCSVTodos(file, fieldNames)
+		case "json-issues":
+			items, err = parseJSONIssues(file, fieldNames)
+		case "todotxt":
+			items, err = parseTodoTxt(file)
+		default:
+			fmt.Printf("Unknown format '%s' (use markdown, csv, json-issues or todotxt)\n", format)
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", args[0], err)
+			return
+		}
+
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		currentBranch := storage_instance.GetCurrentBranch(repo)
+		if currentBranch == nil {
+			fmt.Println("No current branch found")
+			return
+		}
+
+		// Todos created here are exported as todo:<ID>, so importing an export
+		// back recognizes them by their ID
+		existing := make(map[string]bool)
+		for _, todo := range currentBranch.Todos {
+			if todo.ExternalID != "" {
+				existing[todo.ExternalID] = true
+			} else {
+				existing["todo:"+strconv.Itoa(todo.ID)] = true
+			}
+		}
+
+		imported, skipped := 0, 0
+		for _, item := range items {
+			if existing[item.ExternalID] {
+				fmt.Printf("Skipped '%s': already imported\n", item.Title)
+				skipped++
+				continue
+			}
+			existing[item.ExternalID] = true
+
+			todo := models.Todo{
+				ID:          repo.NextTodoID,
+				Title:       item.Title,
+				Description: item.Description,
+				Status:      item.Status,
+				Priority:    item.Priority,
+				CreatedAt:   time.Now(),
+				UpdatedAt:   time.Now(),
+				BranchName:  currentBranch.Name,
+				ExternalID:  item.ExternalID,
+			}
+			currentBranch.Todos = append(currentBranch.Todos, todo)
+			repo.NextTodoID++
+			imported++
+
+			fmt.Printf("Imported todo #%d: %s\n", todo.ID, todo.Title)
+		}
+
+		if dryRun {
+			fmt.Printf("Dry run: would import %d todos, %d already imported\n", imported, skipped)
+			return
+		}
+
+		if imported > 0 {
+			err = storage_instance.SaveRepository(repo)
+			if err != nil {
+				fmt.Printf("Error saving repository: %v\n", err)
+				return
+			}
+		}
+
+		fmt.Printf("Imported %d todos into '%s', skipped %d already imported\n", imported, currentBranch.Name, skipped)
+	},
+}
+
+// importFormatFromPath guesses the import format from a file extension
+func importFormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return "markdown"
+	case ".csv":
+		return "csv"
+	case ".json":
+		return "json-issues"
+	case ".txt":
+		return "todotxt"
+	}
+	return ""
+}
+
+// importFieldMapping applies --map field=column overrides to the default names
+func importFieldMapping(mappings []string) (map[string][]string, error) {
+	fieldNames := make(map[string][]string)
+	for field, names := range importFieldNames {
+		fieldNames[field] = names
+	}
+
+	for _, mapping := range mappings {
+		field, column, ok := strings.Cut(mapping, "=")
+		if _, known := importFieldNames[field]; !ok || !known || column == "" {
+			return nil, fmt.Errorf("invalid mapping '%s' (use field=column with field id, title, description, status or priority)", mapping)
+		}
+		fieldNames[field] = []string{column}
+	}
+
+	return fieldNames, nil
+}
+
+// contentIDs derives stable external IDs for the items of one file from
+// their titles and, for markdown, the heading they are listed under. Items
+// that still share an ID are numbered in file order, so none of them is
+// taken for another.
+type contentIDs map[string]int
+
+func (c contentIDs) id(prefix, section, title string) string {
+	key := strings.ToLower(strings.TrimSpace(title))
+	if section != "" {
+		key = strings.ToLower(strings.TrimSpace(section)) + "\n" + key
+	}
+	sum := sha256.Sum256([]byte(key))
+	id := prefix + ":" + hex.EncodeToString(sum[:])[:16]
+
+	c[id]++
+	if n := c[id]; n > 1 {
+		id += "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+// normalizeStatus maps issue tracker states onto todo statuses
+func normalizeStatus(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "closed", "done", "completed", "complete", "resolved", "fixed", "x":
+		return "completed"
+	case "in progress", "in-progress", "in_progress", "doing", "started", "active", "in review":
+		return "in-progress"
+	}
+	return "pending"
+}
+
+// normalizePriority maps priorities, severities and priority labels onto todo priorities
+func normalizePriority(value string) string {
+	value = strings.ToLower(value)
+	for _, word := range []string{"high", "urgent", "critical", "blocker", "highest", "p0", "p1"} {
+		if strings.Contains(value, word) {
+			return "high"
+		}
+	}
+	for _, word := range []string{"low", "minor", "trivial", "lowest", "p3", "p4"} {
+		if strings.Contains(value, word) {
+			return "low"
+		}
+	}
+	return "medium"
+}
+
+var markdownCheckbox = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s+(.+)$`)
+var markdownHeading = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*$`)
+
+// parseMarkdownChecklist reads "- [ ] title" items. Lines indented below an
+// item that are not checkboxes themselves become its description.
+func parseMarkdownChecklist(r io.Reader) ([]importedTodo, error) {
+	var items []importedTodo
+	ids := contentIDs{}
+	section := ""
+	indent := -1
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if match := markdownCheckbox.FindStringSubmatch(line); match != nil {
+			title := strings.TrimSpace(match[3])
+			status := "pending"
+			if match[2] != " " {
+				status = "completed"
+			}
+			items = append(items, importedTodo{
+				ExternalID: ids.id("markdown", section, title),
+				Title:      title,
+				Status:     status,
+				Priority:   "medium",
+			})
+			indent = len(match[1])
+			continue
+		}
+
+		if match := markdownHeading.FindStringSubmatch(line); match != nil {
+			section = match[1]
+			indent = -1
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if indent >= 0 && trimmed != "" && len(line)-len(strings.TrimLeft(line, " \t")) > indent {
+			item := &items[len(items)-1]
+			item.Description = strings.TrimSpace(item.Description + "\n" + trimmed)
+			continue
+		}
+		indent = -1
+	}
+
+	return items, scanner.Err()
+}
+
+// parseCSVTodos reads a CSV file with a header row
+func parseCSVTodos(r io.Reader, fieldNames map[string][]string) ([]importedTodo, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for field, names := range fieldNames {
+		columns[field] = -1
+		for _, name := range names {
+			for i, column := range header {
+				if columns[field] == -1 && strings.EqualFold(strings.TrimSpace(column), name) {
+					columns[field] = i
+				}
+			}
+		}
+	}
+	if columns["title"] == -1 {
+		return nil, fmt.Errorf("no title column found (use --map title=<column>)")
+	}
+
+	get := func(record []string, field string) string {
+		if i := columns[field]; i >= 0 && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var items []importedTodo
+	ids := contentIDs{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		title := get(record, "title")
+		if title == "" {
+			continue
+		}
+
+		item := importedTodo{
+			Title:       title,
+			Description: get(record, "description"),
+			Status:      normalizeStatus(get(record, "status")),
+			Priority:    normalizePriority(get(record, "priority")),
+		}
+		if id := get(record, "id"); id != "" {
+			item.ExternalID = "csv:" + id
+		} else {
+			item.ExternalID = ids.id("csv", "", title)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// parseJSONIssues reads GitHub or GitLab issue exports. Labels may be strings
+// or objects with a name; the first matching key of each field is used.
+func parseJSONIssues(r io.Reader, fieldNames map[string][]string) ([]importedTodo, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []map[string]interface{}
+	if err := json.Unmarshal(data, &issues); err != nil {
+		var wrapped struct {
+			Issues []map[string]interface{} `json:"issues"`
+		}
+		if json.Unmarshal(data, &wrapped) != nil || wrapped.Issues == nil {
+			return nil, fmt.Errorf("expected a JSON array of issues: %w", err)
+		}
+		issues = wrapped.Issues
+	}
+
+	get := func(issue map[string]interface{}, field string) string {
+		for _, name := range fieldNames[field] {
+			for key, value := range issue {
+				if strings.EqualFold(key, name) && value != nil {
+					return jsonString(value)
+				}
+			}
+		}
+		return ""
+	}
+
+	var items []importedTodo
+	ids := contentIDs{}
+	for _, issue := range issues {
+		title := get(issue, "title")
+		if title == "" {
+			continue
+		}
+
+		item := importedTodo{
+			Title:       title,
+			Description: get(issue, "description"),
+			Status:      normalizeStatus(get(issue, "status")),
+			Priority:    normalizePriority(get(issue, "priority")),
+		}
+		if id := get(issue, "id"); id != "" {
+			item.ExternalID = "issue:" + id
+		} else {
+			item.ExternalID = ids.id("issue", "", title)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// jsonString flattens a JSON value; arrays (such as labels) are joined with commas
+func jsonString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		var parts []string
+		for _, element := range v {
+			parts = append(parts, jsonString(element))
+		}
+		return strings.Join(parts, ",")
+	case map[string]interface{}:
+		if name, ok := v["name"]; ok {
+			return jsonString(name)
+		}
+	}
+	return ""
+}
+
+var todoTxtPriority = regexp.MustCompile(`^\(([A-Z])\)\s+`)
+var todoTxtDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}\s+`)
+
+// parseTodoTxt reads todo.txt lines: "x" marks completion, (A) is high, (B)
+// medium and anything lower is low priority. The ext:, desc: and status: tags
+// written by 'todo export' restore the external ID, description and status.
+func parseTodoTxt(r io.Reader) ([]importedTodo, error) {
+	var items []importedTodo
+	ids := contentIDs{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		item := importedTodo{Status: "pending", Priority: "medium"}
+		if rest, ok := strings.CutPrefix(line, "x "); ok {
+			item.Status = "completed"
+			line = strings.TrimSpace(rest)
+			// Completion and creation dates
+			line = todoTxtDate.ReplaceAllString(line, "")
+			line = todoTxtDate.ReplaceAllString(line, "")
+		}
+		if match := todoTxtPriority.FindStringSubmatch(line); match != nil {
+			item.Priority = todoTxtPriorityName(match[1])
+			line = line[len(match[0]):]
+		}
+		line = todoTxtDate.ReplaceAllString(line, "")
+
+		var words []string
+		for _, field := range strings.Fields(line) {
+			key, value, _ := strings.Cut(field, ":")
+			switch {
+			// Completed todo.txt tasks may carry their priority as pri:A
+			case key == "pri" && len(value) == 1:
+				item.Priority = todoTxtPriorityName(value)
+			case key == "ext" && value != "":
+				item.ExternalID = todoTxtUnescape(value)
+			case key == "desc":
+				item.Description = todoTxtUnescape(value)
+			case key == "status" && models.ValidStatus(value) && item.Status != "completed":
+				item.Status = value
+			default:
+				words = append(words, field)
+			}
+		}
+
+		item.Title = strings.Join(words, " ")
+		if item.ExternalID == "" {
+			item.ExternalID = ids.id("todotxt", "", item.Title)
+		}
+		items = append(items, item)
+	}
+
+	return items, scanner.Err()
+}
+
+// todoTxtPriorityName maps a todo.txt priority letter to a todo priority
+func todoTxtPriorityName(letter string) string {
+	switch letter {
+	case "A":
+		return "high"
+	case "B":
+		return "medium"
+	}
+	return "low"
+}
+
+// todoTxtUnescape decodes a percent-encoded tag value, keeping values that
+// are not valid encodings as written
+func todoTxtUnescape(value string) string {
+	if decoded, err := url.PathUnescape(value); err == nil {
+		return decoded
+	}
+	return value
+}
+
+func init() {
+	ImportCmd.Flags().StringP("format", "f", "", "Input format: markdown, csv, json-issues or todotxt (default: from the file extension)")
+	ImportCmd.Flags().StringSlice("map", nil, "Map a todo field to a CSV column or JSON key, e.g. title=Summary")
+	ImportCmd.Flags().Bool("dry-run", false, "Show what would be imported without saving")
+}
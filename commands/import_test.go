@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMarkdownChecklist(t *testing.T) {
+	input := `# Backend
+- [ ] Write tests
+  cover the parser
+- [x] Ship
+
+## Frontend
+- [ ] Write tests
+* [ ] Write tests
+Not a todo
+`
+	items, err := parseMarkdownChecklist(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("items = %+v, want 4", items)
+	}
+	if item := items[0]; item.Title != "Write tests" || item.Description != "cover the parser" || item.Status != "pending" {
+		t.Errorf("item 0 = %+v", item)
+	}
+	if item := items[1]; item.Title != "Ship" || item.Status != "completed" {
+		t.Errorf("item 1 = %+v", item)
+	}
+
+	// The same title under another heading, or twice under one, is another item
+	ids := map[string]bool{}
+	for _, item := range items {
+		ids[item.ExternalID] = true
+	}
+	if len(ids) != 4 {
+		t.Errorf("external IDs = %v, want 4 distinct", ids)
+	}
+
+	// IDs are stable across imports of the same file
+	again, _ := parseMarkdownChecklist(strings.NewReader(input))
+	for i := range items {
+		if again[i].ExternalID != items[i].ExternalID {
+			t.Errorf("item %d ID changed from %s to %s", i, items[i].ExternalID, again[i].ExternalID)
+		}
+	}
+}
+
+func TestParseCSVTodos(t *testing.T) {
+	input := `Key,Summary,Status,Priority,Details
+PROJ-1,Fix login,In Progress,Critical,"Users see ""500"""
+,Write tests,Done,Minor,
+,Write tests,Open,,
+`
+	fieldNames, err := importFieldMapping([]string{"title=Summary"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := parseCSVTodos(strings.NewReader(input), fieldNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("items = %+v, want 3", items)
+	}
+	want := importedTodo{ExternalID: "csv:PROJ-1", Title: "Fix login", Description: `Users see "500"`, Status: "in-progress", Priority: "high"}
+	if items[0] != want {
+		t.Errorf("item 0 = %+v, want %+v", items[0], want)
+	}
+	if item := items[1]; item.Status != "completed" || item.Priority != "low" || !strings.HasPrefix(item.ExternalID, "csv:") {
+		t.Errorf("item 1 = %+v", item)
+	}
+	if items[1].ExternalID == items[2].ExternalID {
+		t.Errorf("rows with the same title share the ID %s", items[1].ExternalID)
+	}
+
+	if _, err := parseCSVTodos(strings.NewReader("Key,Name2\n1,x\n"), importFieldNames); err == nil {
+		t.Error("CSV without a title column was accepted")
+	}
+}
+
+func TestParseJSONIssues(t *testing.T) {
+	input := `{"issues": [
+		{"number": 12, "title": "Crash on start", "body": "Stack trace", "state": "closed", "labels": [{"name": "bug"}, {"name": "P1"}]},
+		{"title": "Docs", "state": "open", "labels": ["low"]},
+		{"title": "Docs", "state": "open"},
+		{"body": "no title"}
+	]}`
+	items, err := parseJSONIssues(strings.NewReader(input), importFieldNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("items = %+v, want 3", items)
+	}
+	want := importedTodo{ExternalID: "issue:12", Title: "Crash on start", Description: "Stack trace", Status: "completed", Priority: "high"}
+	if items[0] != want {
+		t.Errorf("item 0 = %+v, want %+v", items[0], want)
+	}
+	if item := items[1]; item.Status != "pending" || item.Priority != "low" {
+		t.Errorf("item 1 = %+v", item)
+	}
+	if items[1].ExternalID == items[2].ExternalID {
+		t.Errorf("issues with the same title share the ID %s", items[1].ExternalID)
+	}
+
+	if _, err := parseJSONIssues(strings.NewReader(`{"total": 1}`), importFieldNames); err == nil {
+		t.Error("JSON without issues was accepted")
+	}
+}
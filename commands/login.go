@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"todo-cli/remote"
+	"todo-cli/storage"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var LoginCmd = &cobra.Command{
+	Use:   "login [remote]",
+	Short: "Log in to a remote and store an API token for it",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		remoteName := "origin"
+		if len(args) > 0 {
+			remoteName = args[0]
+		}
+		username, _ := cmd.Flags().GetString("username")
+		helper, _ := cmd.Flags().GetString("helper")
+
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		targetRemote := storage_instance.GetRemoteByName(repo, remoteName)
+		if targetRemote == nil {
+			fmt.Printf("Remote '%s' not found\n", remoteName)
+			return
+		}
+
+		reader := bufio.NewReader(os.Stdin)
+		if username == "" {
+			fmt.Print("Username: ")
+			line, _ := reader.ReadString('\n')
+			username = strings.TrimSpace(line)
+		}
+
+		fmt.Print("Password: ")
+		var password string
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			data, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Println()
+			if err != nil {
+				fmt.Printf("Error reading password: %v\n", err)
+				return
+			}
+			password = string(data)
+		} else {
+			line, _ := reader.ReadString('\n')
+			password = strings.TrimSpace(line)
+		}
+
+		token, err := remoteService.Login(*targetRemote, username, password)
+		if err != nil {
+			fmt.Printf("Login failed: %v\n", err)
+			return
+		}
+
+		if helper != "" {
+			targetRemote.CredentialHelper = helper
+			err = storage_instance.SaveRepository(repo)
+			if err != nil {
+				fmt.Printf("Error saving repository: %v\n", err)
+				return
+			}
+		}
+
+		if targetRemote.CredentialHelper != "" {
+			_, err = remote.RunCredentialHelper(targetRemote.CredentialHelper, "store", *targetRemote, &remote.Credential{Username: username, Token: token})
+			if err != nil {
+				fmt.Printf("Error storing credentials: %v\n", err)
+				return
+			}
+		} else {
+			credentials, err := storage_instance.LoadCredentials()
+			if err != nil {
+				fmt.Printf("Error loading credentials: %v\n", err)
+				return
+			}
+			credentials[storage.CredentialKey(targetRemote.URL)] = storage.Credential{Username: username, Token: token}
+			err = storage_instance.SaveCredentials(credentials)
+			if err != nil {
+				fmt.Printf("Error saving credentials: %v\n", err)
+				return
+			}
+		}
+
+		fmt.Printf("Logged in to %s as %s\n", targetRemote.Name, username)
+	},
+}
+
+var LogoutCmd = &cobra.Command{
+	Use:   "logout [remote]",
+	Short: "Remove the stored credentials for a remote",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		remoteName := "origin"
+		if len(args) > 0 {
+			remoteName = args[0]
+		}
+
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		targetRemote := storage_instance.GetRemoteByName(repo, remoteName)
+		if targetRemote == nil {
+			fmt.Printf("Remote '%s' not found\n", remoteName)
+			return
+		}
+
+		if targetRemote.CredentialHelper != "" {
+			_, err = remote.RunCredentialHelper(targetRemote.CredentialHelper, "erase", *targetRemote, nil)
+			if err != nil {
+				fmt.Printf("Error erasing credentials: %v\n", err)
+				return
+			}
+		}
+
+		err = storage_instance.ForgetCredentials(targetRemote.URL)
+		if err != nil {
+			fmt.Printf("Error removing credentials: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Logged out of %s\n", targetRemote.Name)
+	},
+}
+
+func init() {
+	LoginCmd.Flags().StringP("username", "u", "", "Username (prompted if not given)")
+	LoginCmd.Flags().String("helper", "", "Credential helper to store the token with (e.g. 'store' runs todo-credential-store)")
+}
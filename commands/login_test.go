@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"todo-cli/models"
+)
+
+// loginTestSetup saves a repository whose origin is a server handing out
+// the token "secret" to alice, and returns the server's URL
+func loginTestSetup(t *testing.T) string {
+	t.Helper()
+	useTempHome(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if username, password, _ := req.BasicAuth(); username != "alice" || password != "hunter2" {
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+	}))
+	t.Cleanup(server.Close)
+
+	saveTestRepository(t, &models.Repository{
+		Branches:      []models.Branch{{Name: "main", IsActive: true}},
+		CurrentBranch: "main",
+		Remotes:       []models.Remote{{Name: "origin", URL: server.URL, Type: "http"}},
+	})
+	return server.URL
+}
+
+// login runs 'todo login origin -u alice' with the password on stdin
+func login(t *testing.T) {
+	t.Helper()
+	stdin := filepath.Join(t.TempDir(), "stdin")
+	writeFile(t, stdin, "hunter2\n")
+	f, err := os.Open(stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	previous := os.Stdin
+	os.Stdin = f
+	defer func() { os.Stdin = previous }()
+
+	setFlags(t, LoginCmd, map[string]string{"username": "alice"})
+	captureStdout(t, func() { LoginCmd.Run(LoginCmd, []string{"origin"}) })
+}
+
+func TestLoginAndLogout(t *testing.T) {
+	url := loginTestSetup(t)
+
+	login(t)
+	credentials, err := storage_instance.LoadCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := credentials[url]; got.Username != "alice" || got.Token != "secret" {
+		t.Fatalf("credentials = %+v, want alice's token stored for %s", credentials, url)
+	}
+
+	captureStdout(t, func() { LogoutCmd.Run(LogoutCmd, []string{"origin"}) })
+	if credentials, _ := storage_instance.LoadCredentials(); len(credentials) != 0 {
+		t.Errorf("credentials after logout = %+v", credentials)
+	}
+}
+
+func TestRemoteChangesDropCredentials(t *testing.T) {
+	loginTestSetup(t)
+	login(t)
+
+	setFlags(t, remoteUpdateCmd, map[string]string{"url": "https://other.example.com"})
+	captureStdout(t, func() { remoteUpdateCmd.Run(remoteUpdateCmd, []string{"origin"}) })
+	if credentials, _ := storage_instance.LoadCredentials(); len(credentials) != 0 {
+		t.Errorf("credentials after changing the URL = %+v", credentials)
+	}
+
+	loginTestSetup(t)
+	login(t)
+	captureStdout(t, func() { remoteRemoveCmd.Run(remoteRemoveCmd, []string{"origin"}) })
+	if credentials, _ := storage_instance.LoadCredentials(); len(credentials) != 0 {
+		t.Errorf("credentials after removing the remote = %+v", credentials)
+	}
+}
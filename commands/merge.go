@@ -3,6 +3,9 @@ package commands
 import (
 	"fmt"
 	"time"
+	"todo-cli/diff"
+	"todo-cli/models"
+	"todo-cli/storage"
 
 	"github.com/spf13/cobra"
 )
@@ -13,6 +16,9 @@ var MergeCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		sourceBranch := args[0]
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		format, _ := cmd.Flags().GetString("format")
+		noVerify, _ := cmd.Flags().GetBool("no-verify")
 
 		repo, err := storage_instance.LoadRepository()
 		if err != nil {
@@ -38,39 +44,41 @@ var MergeCmd = &cobra.Command{
 			return
 		}
 
-		// Merge todos from source branch
-		mergedCount := 0
-		for _, sourceTodo := range sourceB.Todos {
-			// Check if todo already exists in current branch (by ID)
-			exists := false
-			for _, currentTodo := range currentBranch.Todos {
-				if currentTodo.ID == sourceTodo.ID {
-					exists = true
-					break
-				}
-			}
+		result := mergeBranchTodos(currentBranch, sourceB)
 
-			if !exists {
-				// Update branch name and add to current branch
-				mergedTodo := sourceTodo
-				mergedTodo.BranchName = currentBranch.Name
-				mergedTodo.UpdatedAt = time.Now()
-
-				// Add to current branch
-				for i := range repo.Branches {
-					if repo.Branches[i].Name == currentBranch.Name {
-						repo.Branches[i].Todos = append(repo.Branches[i].Todos, mergedTodo)
-						mergedCount++
-						break
-					}
-				}
+		if dryRun {
+			printDiff(format, []diff.Result{diff.Compare(currentBranch.Name, currentBranch.Todos, currentBranch.Name+" (after merge)", result.Todos)})
+			return
+		}
+
+		if !noVerify {
+			err = runHook(hookPreMerge, map[string]interface{}{
+				"source":    sourceBranch,
+				"target":    currentBranch.Name,
+				"added":     result.Added,
+				"updated":   result.Updated,
+				"conflicts": result.Conflicts,
+			})
+			if err != nil {
+				fmt.Printf("Merge aborted: %v\n", err)
+				return
 			}
 		}
 
-		// Merge commits from source branch
+		currentBranch.Todos = result.Todos
+		mergedCount := result.Added + result.Updated
+		recordMergeBase(currentBranch, sourceB)
+
+		// Merge commits from source branch, skipping those an earlier merge brought over
+		merged := make(map[string]bool)
+		for _, commit := range repo.Commits {
+			if commit.Branch == currentBranch.Name {
+				merged[commit.ID] = true
+			}
+		}
 		mergedCommits := 0
 		for _, commit := range repo.Commits {
-			if commit.Branch == sourceBranch {
+			if commit.Branch == sourceBranch && !merged[commit.ID] {
 				// Create a new commit entry for the current branch
 				mergedCommit := commit
 				mergedCommit.Branch = currentBranch.Name
@@ -91,6 +99,18 @@ var MergeCmd = &cobra.Command{
 		fmt.Printf("Merged branch '%s' into '%s'\n", sourceBranch, currentBranch.Name)
 		fmt.Printf("- %d todos merged\n", mergedCount)
 		fmt.Printf("- %d commits merged\n", mergedCommits)
+		for _, id := range result.Conflicts {
+			fmt.Printf("- conflict: todo #%d changed on both branches, kept '%s' version\n", id, currentBranch.Name)
+		}
+
+		runPostHook(hookPostMerge, map[string]interface{}{
+			"source":    sourceBranch,
+			"target":    currentBranch.Name,
+			"added":     result.Added,
+			"updated":   result.Updated,
+			"conflicts": result.Conflicts,
+			"commits":   mergedCommits,
+		})
 
 		// Ask if user wants to delete the source branch
 		fmt.Printf("\nDelete source branch '%s'? (y/N): ", sourceBranch)
@@ -98,12 +118,10 @@ var MergeCmd = &cobra.Command{
 		fmt.Scanln(&response)
 
 		if response == "y" || response == "Y" {
-			// Remove source branch
-			for i, branch := range repo.Branches {
-				if branch.Name == sourceBranch {
-					repo.Branches = append(repo.Branches[:i], repo.Branches[i+1:]...)
-					break
-				}
+			// Merged todos now live on the current branch, so no force is needed
+			if err := storage.DeleteBranch(repo, sourceBranch, false); err != nil {
+				fmt.Printf("Error deleting branch: %v\n", err)
+				return
 			}
 
 			err = storage_instance.SaveRepository(repo)
@@ -116,3 +134,96 @@ var MergeCmd = &cobra.Command{
 		}
 	},
 }
+
+// mergeResult holds the outcome of merging one branch's todos into another
+type mergeResult struct {
+	Todos     []models.Todo
+	Added     int
+	Updated   int
+	Conflicts []int
+}
+
+// mergeBranchTodos merges the todos of source into target. Todos missing from
+// target are added. When the branches share a merge base, todos changed only on
+// source since the base are updated and todos changed on both sides are
+// reported as conflicts, keeping the target version.
+func mergeBranchTodos(target, source *models.Branch) mergeResult {
+	result := mergeResult{Todos: append([]models.Todo{}, target.Todos...)}
+
+	// The merge base is the state of the last merge between the two branches,
+	// or else the snapshot taken when one branch was created from the other
+	base := make(map[int]models.Todo)
+	if last, ok := target.MergeBases[source.Name]; ok {
+		for _, todo := range last {
+			base[todo.ID] = todo
+		}
+	} else if source.Parent == target.Name {
+		for _, todo := range source.Base {
+			base[todo.ID] = todo
+		}
+	} else if target.Parent == source.Name {
+		for _, todo := range target.Base {
+			base[todo.ID] = todo
+		}
+	}
+
+	for _, sourceTodo := range source.Todos {
+		index := -1
+		for i := range result.Todos {
+			if result.Todos[i].ID == sourceTodo.ID {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			// Update branch name and add to target branch
+			mergedTodo := sourceTodo
+			mergedTodo.BranchName = target.Name
+			mergedTodo.UpdatedAt = time.Now()
+			result.Todos = append(result.Todos, mergedTodo)
+			result.Added++
+			continue
+		}
+
+		baseTodo, ok := base[sourceTodo.ID]
+		if !ok {
+			continue
+		}
+
+		targetTodo := result.Todos[index]
+		sourceChanged := storage.TodoChanged(baseTodo, sourceTodo)
+		targetChanged := storage.TodoChanged(baseTodo, targetTodo)
+
+		switch {
+		case sourceChanged && !targetChanged:
+			applyTodoState(&result.Todos[index], sourceTodo)
+			result.Updated++
+		case sourceChanged && targetChanged && storage.TodoChanged(sourceTodo, targetTodo):
+			result.Conflicts = append(result.Conflicts, sourceTodo.ID)
+		}
+	}
+
+	return result
+}
+
+// recordMergeBase stores source's todos as the merge base of both branches.
+// After the merge target holds every change of source, so a later merge only
+// looks at what changed since this one.
+func recordMergeBase(target, source *models.Branch) {
+	snapshot := append([]models.Todo{}, source.Todos...)
+	if target.MergeBases == nil {
+		target.MergeBases = make(map[string][]models.Todo)
+	}
+	if source.MergeBases == nil {
+		source.MergeBases = make(map[string][]models.Todo)
+	}
+	target.MergeBases[source.Name] = snapshot
+	source.MergeBases[target.Name] = snapshot
+}
+
+func init() {
+	MergeCmd.Flags().Bool("dry-run", false, "Show what the merge would change without applying it")
+	MergeCmd.Flags().StringP("format", "f", "text", "Dry-run output format (text, json)")
+	MergeCmd.Flags().Bool("no-verify", false, "Skip the pre-merge hook")
+}
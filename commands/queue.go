@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+	"todo-cli/models"
+	"todo-cli/remote"
+	"todo-cli/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// quickFlushTimeout bounds each request when the queue is flushed before an
+// unrelated command, so being offline does not stall every command
+const quickFlushTimeout = 5 * time.Second
+
+func init() {
+	remoteService.OnRetry = func(r models.Remote, attempt int, wait time.Duration, err error) {
+		// stderr keeps retries out of output read by other programs, such as pull -o json
+		fmt.Fprintf(os.Stderr, "Attempt %d for %s failed: %v, retrying in %s\n", attempt, r.Name, err, wait.Round(100*time.Millisecond))
+	}
+}
+
+// queuePush records a push that failed because the remote was unreachable.
+// Forced pushes are never queued: a queued push is delivered later without
+// anyone confirming that the remote may be overwritten.
+func queuePush(remoteName string, pushErr error) error {
+	pending, err := storage_instance.LoadPendingPushes()
+	if err != nil {
+		return err
+	}
+
+	for i := range pending {
+		if pending[i].Remote == remoteName {
+			pending[i].LastError = pushErr.Error()
+			return storage_instance.SavePendingPushes(pending)
+		}
+	}
+
+	pending = append(pending, storage.PendingPush{
+		Remote:    remoteName,
+		QueuedAt:  time.Now(),
+		LastError: pushErr.Error(),
+	})
+	return storage_instance.SavePendingPushes(pending)
+}
+
+// clearPendingPush drops the queued push for a remote after a successful push
+func clearPendingPush(remoteName string) {
+	pending, err := storage_instance.LoadPendingPushes()
+	if err != nil || len(pending) == 0 {
+		return
+	}
+
+	kept := pending[:0]
+	for _, p := range pending {
+		if p.Remote != remoteName {
+			kept = append(kept, p)
+		}
+	}
+	storage_instance.SavePendingPushes(kept)
+}
+
+// FlushPendingPushes runs before every command and retries queued pushes with
+// a single short attempt each before pull and fetch, the other commands that
+// talk to remotes. Push and sync flush on their own, and local commands never
+// wait for the network. Messages go to stderr so pull and fetch output stays
+// readable by other programs.
+func FlushPendingPushes(cmd *cobra.Command) {
+	if cmd != PullCmd && cmd != FetchCmd {
+		return
+	}
+	flushPendingPushes(true, false, nil)
+}
+
+// flushPendingPushes pushes the current repository, not the one at the time
+// of the failed push, to every remote with a queued push. The pre-push hook
+// runs first unless noVerify is set, and a failing hook drops the push like a
+// rejection does. Pushes that still cannot reach their remote stay queued;
+// pushes the remote rejects are dropped with a message, since retrying them
+// cannot succeed. Remotes in skip keep their queued push untouched, such as
+// those that a sync has just tried to push to.
+func flushPendingPushes(quick, noVerify bool, skip map[string]bool) {
+	pending, err := storage_instance.LoadPendingPushes()
+	if err != nil || len(pending) == 0 {
+		return
+	}
+
+	repo, err := storage_instance.LoadRepository()
+	if err != nil {
+		return
+	}
+
+	var kept []storage.PendingPush
+	for _, p := range pending {
+		if skip[p.Remote] {
+			kept = append(kept, p)
+			continue
+		}
+		target := storage_instance.GetRemoteByName(repo, p.Remote)
+		if target == nil {
+			continue // Remote was removed
+		}
+		queuedAt := p.QueuedAt.Format("2006-01-02 15:04:05")
+
+		if !noVerify {
+			err := runHookTo(hookPrePush, map[string]interface{}{
+				"remote":   target.Name,
+				"url":      target.URL,
+				"force":    false,
+				"branch":   repo.CurrentBranch,
+				"branches": len(repo.Branches),
+				"commits":  repo.Commits,
+				"queued":   true,
+			}, os.Stderr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Dropped the push to %s queued %s: %v\n", p.Remote, queuedAt, err)
+				continue
+			}
+		}
+
+		r := *target
+		if quick {
+			r.MaxAttempts = 1
+			if timeout, err := remote.RemoteTimeout(r); err != nil || timeout > quickFlushTimeout {
+				r.Timeout = quickFlushTimeout.String()
+			}
+		}
+
+		err := remoteService.PushRepository(r, repo, false)
+		switch {
+		case err == nil:
+			fmt.Fprintf(os.Stderr, "Pushed the current repository to %s for a push queued %s\n", p.Remote, queuedAt)
+		case remote.IsTransient(err):
+			p.LastError = err.Error()
+			kept = append(kept, p)
+			if !quick {
+				fmt.Fprintf(os.Stderr, "%s is still unreachable, push stays queued: %v\n", p.Remote, err)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Queued push to %s failed: %v\n", p.Remote, err)
+		}
+	}
+
+	storage_instance.SavePendingPushes(kept)
+}
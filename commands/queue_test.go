@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"todo-cli/models"
+	"todo-cli/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// queueTestSetup saves a repository with a file remote that has a queued
+// push, and returns the remote's file
+func queueTestSetup(t *testing.T) string {
+	t.Helper()
+	useTempHome(t)
+
+	target := filepath.Join(t.TempDir(), "remote.json")
+	saveTestRepository(t, &models.Repository{
+		Branches: []models.Branch{{
+			Name:     "main",
+			IsActive: true,
+			Todos:    []models.Todo{{ID: 1, Title: "Written offline", Status: "pending", BranchName: "main"}},
+		}},
+		CurrentBranch: "main",
+		NextTodoID:    2,
+		Remotes:       []models.Remote{{Name: "backup", URL: target, Type: "file"}},
+	})
+	err := storage_instance.SavePendingPushes([]storage.PendingPush{{Remote: "backup", LastError: "connection refused"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return target
+}
+
+// installHook writes an executable hook script
+func installHook(t *testing.T, name, script string) {
+	t.Helper()
+	path := storage_instance.HookPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pendingPushes(t *testing.T) []storage.PendingPush {
+	t.Helper()
+	pending, err := storage_instance.LoadPendingPushes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pending
+}
+
+func TestFlushPushesCurrentRepository(t *testing.T) {
+	target := queueTestSetup(t)
+	payload := filepath.Join(t.TempDir(), "payload")
+	installHook(t, hookPrePush, "#!/bin/sh\ncat > '"+payload+"'\n")
+
+	flushPendingPushes(false, false, nil)
+
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("queued push was not delivered: %v", err)
+	}
+	var pushed models.Repository
+	if err := json.Unmarshal(data, &pushed); err != nil {
+		t.Fatal(err)
+	}
+	if len(pushed.Branches) != 1 || pushed.Branches[0].Todos[0].Title != "Written offline" {
+		t.Errorf("pushed = %+v", pushed)
+	}
+	if pending := pendingPushes(t); len(pending) != 0 {
+		t.Errorf("queue after delivery = %+v", pending)
+	}
+
+	var input map[string]interface{}
+	hookInput, _ := os.ReadFile(payload)
+	if err := json.Unmarshal(hookInput, &input); err != nil {
+		t.Fatalf("pre-push hook did not run: %v", err)
+	}
+	if input["remote"] != "backup" || input["queued"] != true {
+		t.Errorf("hook input = %v", input)
+	}
+}
+
+func TestFlushDropsPushRefusedByHook(t *testing.T) {
+	target := queueTestSetup(t)
+	installHook(t, hookPrePush, "#!/bin/sh\nexit 1\n")
+
+	flushPendingPushes(false, false, nil)
+
+	if _, err := os.Stat(target); err == nil {
+		t.Error("push refused by the pre-push hook was delivered")
+	}
+	if pending := pendingPushes(t); len(pending) != 0 {
+		t.Errorf("queue = %+v, want the refused push dropped", pending)
+	}
+}
+
+func TestFlushOnlyBeforeRemoteCommands(t *testing.T) {
+	target := queueTestSetup(t)
+
+	for _, cmd := range []*cobra.Command{todoListCmd, ExportCmd, gitPrepareCommitMsgCmd} {
+		FlushPendingPushes(cmd)
+	}
+	if _, err := os.Stat(target); err == nil {
+		t.Fatal("a local command flushed the queue")
+	}
+
+	FlushPendingPushes(FetchCmd)
+	if _, err := os.Stat(target); err != nil {
+		t.Errorf("fetch did not flush the queue: %v", err)
+	}
+}
+
+func TestFlushSkipsRemotes(t *testing.T) {
+	target := queueTestSetup(t)
+
+	flushPendingPushes(false, false, map[string]bool{"backup": true})
+
+	if _, err := os.Stat(target); err == nil {
+		t.Error("push queued for a skipped remote was delivered")
+	}
+	if pending := pendingPushes(t); len(pending) != 1 || pending[0].Remote != "backup" {
+		t.Errorf("queue = %+v, want the skipped push kept", pending)
+	}
+}
+
+func TestForcedPushIsNotQueued(t *testing.T) {
+	useTempHome(t)
+	saveTestRepository(t, &models.Repository{
+		Branches:      []models.Branch{{Name: "main", IsActive: true}},
+		CurrentBranch: "main",
+		Remotes:       []models.Remote{{Name: "origin", URL: "http://127.0.0.1:1", Type: "http", MaxAttempts: 1}},
+	})
+
+	setFlags(t, PushCmd, map[string]string{"force": "true"})
+	out := captureStdout(t, func() { PushCmd.Run(PushCmd, []string{"origin"}) })
+
+	if pending := pendingPushes(t); len(pending) != 0 || !strings.Contains(out, "not queued") {
+		t.Errorf("queue = %+v, output %q, want forced pushes left out", pending, out)
+	}
+}
@@ -1,10 +1,15 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
 	"github.com/spf13/cobra"
+	"todo-cli/diff"
 	"todo-cli/models"
 	"todo-cli/remote"
+	"todo-cli/storage"
 )
 
 var remoteService = remote.NewRemoteService()
@@ -23,6 +28,13 @@ var remoteAddCmd = &cobra.Command{
 		url := args[1]
 		
 		remoteType, _ := cmd.Flags().GetString("type")
+		credentialHelper, _ := cmd.Flags().GetString("credential-helper")
+		caCert, _ := cmd.Flags().GetString("ca-cert")
+		clientCert, _ := cmd.Flags().GetString("client-cert")
+		clientKey, _ := cmd.Flags().GetString("client-key")
+		insecure, _ := cmd.Flags().GetBool("insecure-skip-verify")
+		maxAttempts, _ := cmd.Flags().GetInt("max-attempts")
+		timeout, _ := cmd.Flags().GetString("timeout")
 		
 		repo, err := storage_instance.LoadRepository()
 		if err != nil {
@@ -40,9 +52,30 @@ var remoteAddCmd = &cobra.Command{
 		
 		// Add new remote
 		newRemote := models.Remote{
-			Name: name,
-			URL:  url,
-			Type: remoteType,
+			Name:               name,
+			URL:                url,
+			Type:               remoteType,
+			CredentialHelper:   credentialHelper,
+			CACert:             caCert,
+			ClientCert:         clientCert,
+			ClientKey:          clientKey,
+			InsecureSkipVerify: insecure,
+			MaxAttempts:        maxAttempts,
+			Timeout:            timeout,
+		}
+		
+		if newRemote.Type == "" {
+			newRemote.Type = remote.InferType(url)
+		}
+		newRemote.Type = strings.ToLower(newRemote.Type)
+		if _, err := remoteService.Transport(newRemote.Type); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		
+		if err := validateRemote(newRemote); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
 		}
 		
 		repo.Remotes = append(repo.Remotes, newRemote)
@@ -53,10 +86,153 @@ var remoteAddCmd = &cobra.Command{
 			return
 		}
 		
-		fmt.Printf("Added remote '%s': %s (%s)\n", name, url, remoteType)
+		fmt.Printf("Added remote '%s': %s (%s)\n", name, url, newRemote.Type)
+		if insecure {
+			fmt.Println("Warning: certificate verification is disabled for this remote, use only for testing")
+		}
+	},
+}
+
+var remoteUpdateCmd = &cobra.Command{
+	Use:   "update [name]",
+	Short: "Update the URL, type, credential helper, TLS or retry settings of a remote",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+		
+		targetRemote := storage_instance.GetRemoteByName(repo, name)
+		if targetRemote == nil {
+			fmt.Printf("Remote '%s' not found\n", name)
+			return
+		}
+		
+		// Only change the settings that were given
+		flags := cmd.Flags()
+		oldURL := targetRemote.URL
+		if flags.Changed("url") || flags.Changed("type") {
+			if flags.Changed("url") {
+				targetRemote.URL, _ = flags.GetString("url")
+			}
+			// A new URL may need another transport unless --type names one
+			remoteType, _ := flags.GetString("type")
+			if remoteType == "" {
+				remoteType = remote.InferType(targetRemote.URL)
+			}
+			targetRemote.Type = strings.ToLower(remoteType)
+			if _, err := remoteService.Transport(targetRemote.Type); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+		}
+		if flags.Changed("credential-helper") {
+			targetRemote.CredentialHelper, _ = flags.GetString("credential-helper")
+		}
+		if flags.Changed("ca-cert") {
+			targetRemote.CACert, _ = flags.GetString("ca-cert")
+		}
+		if flags.Changed("client-cert") {
+			targetRemote.ClientCert, _ = flags.GetString("client-cert")
+		}
+		if flags.Changed("client-key") {
+			targetRemote.ClientKey, _ = flags.GetString("client-key")
+		}
+		if flags.Changed("insecure-skip-verify") {
+			targetRemote.InsecureSkipVerify, _ = flags.GetBool("insecure-skip-verify")
+		}
+		if flags.Changed("max-attempts") {
+			targetRemote.MaxAttempts, _ = flags.GetInt("max-attempts")
+		}
+		if flags.Changed("timeout") {
+			targetRemote.Timeout, _ = flags.GetString("timeout")
+		}
+		
+		if err := validateRemote(*targetRemote); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		
+		err = storage_instance.SaveRepository(repo)
+		if err != nil {
+			fmt.Printf("Error saving repository: %v\n", err)
+			return
+		}
+		
+		if targetRemote.URL != oldURL {
+			forgetUnusedCredentials(repo, oldURL)
+		}
+		
+		fmt.Printf("Updated remote '%s'\n", name)
+		if targetRemote.InsecureSkipVerify {
+			fmt.Println("Warning: certificate verification is disabled for this remote, use only for testing")
+		}
 	},
 }
 
+// forgetUnusedCredentials drops the stored login for a URL no remote uses
+// anymore, so it is not sent to whatever server a new remote of the same
+// name points at
+func forgetUnusedCredentials(repo *models.Repository, url string) {
+	for _, r := range repo.Remotes {
+		if storage.CredentialKey(r.URL) == storage.CredentialKey(url) {
+			return
+		}
+	}
+	if err := storage_instance.ForgetCredentials(url); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
+
+// validateOutput checks the value of an --output flag
+func validateOutput(output string) error {
+	if output != "text" && output != "json" {
+		return fmt.Errorf("invalid output format '%s' (valid: text, json)", output)
+	}
+	return nil
+}
+
+// printError reports a failure as text, or for json output as an object with
+// an "error" key so programs reading the output can still parse it
+func printError(output, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if output == "json" {
+		json.NewEncoder(os.Stdout).Encode(map[string]string{"error": message})
+		return
+	}
+	fmt.Println(message)
+}
+
+// printMergeReport writes a merge report in the requested output format
+func printMergeReport(report *remote.MergeReport, verbose bool, output string) {
+	if output == "json" {
+		if err := report.WriteJSON(os.Stdout); err != nil {
+			fmt.Printf("Error writing report: %v\n", err)
+		}
+		return
+	}
+	report.WriteText(os.Stdout, verbose)
+}
+
+// validateRemote checks that client certificate settings are complete and
+// the retry settings make sense
+func validateRemote(r models.Remote) error {
+	if (r.ClientCert == "") != (r.ClientKey == "") {
+		return fmt.Errorf("--client-cert and --client-key must be given together")
+	}
+	if r.MaxAttempts < 0 {
+		return fmt.Errorf("--max-attempts must not be negative")
+	}
+	if _, err := remote.RemoteTimeout(r); err != nil {
+		return err
+	}
+	return nil
+}
+
 var remoteListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List remote repositories",
@@ -72,9 +248,20 @@ var remoteListCmd = &cobra.Command{
 			return
 		}
 		
+		queued := make(map[string]bool)
+		if pending, err := storage_instance.LoadPendingPushes(); err == nil {
+			for _, p := range pending {
+				queued[p.Remote] = true
+			}
+		}
+		
 		fmt.Println("Remotes:")
 		for _, remote := range repo.Remotes {
-			fmt.Printf("  %s\t%s (%s)\n", remote.Name, remote.URL, remote.Type)
+			fmt.Printf("  %s\t%s (%s)", remote.Name, remote.URL, remote.Type)
+			if queued[remote.Name] {
+				fmt.Print(" [push queued]")
+			}
+			fmt.Println()
 		}
 	},
 }
@@ -94,8 +281,10 @@ var remoteRemoveCmd = &cobra.Command{
 		
 		// Find and remove remote
 		found := false
+		var removedURL string
 		for i, remote := range repo.Remotes {
 			if remote.Name == name {
+				removedURL = remote.URL
 				repo.Remotes = append(repo.Remotes[:i], repo.Remotes[i+1:]...)
 				found = true
 				break
@@ -112,6 +301,7 @@ var remoteRemoveCmd = &cobra.Command{
 			fmt.Printf("Error saving repository: %v\n", err)
 			return
 		}
+		forgetUnusedCredentials(repo, removedURL)
 		
 		fmt.Printf("Removed remote '%s'\n", name)
 	},
@@ -149,11 +339,44 @@ var PushCmd = &cobra.Command{
 		
 		fmt.Printf("Pushing to %s (%s)...\n", targetRemote.Name, targetRemote.URL)
 		
-		err = remoteService.PushRepository(*targetRemote, repo)
+		force, _ := cmd.Flags().GetBool("force")
+		if noVerify, _ := cmd.Flags().GetBool("no-verify"); !noVerify {
+			err = runHook(hookPrePush, map[string]interface{}{
+				"remote":   targetRemote.Name,
+				"url":      targetRemote.URL,
+				"force":    force,
+				"branch":   repo.CurrentBranch,
+				"branches": len(repo.Branches),
+				"commits":  repo.Commits,
+				"queued":   false,
+			})
+			if err != nil {
+				fmt.Printf("Push aborted: %v\n", err)
+				return
+			}
+		}
+		
+		if force {
+			if transport, err := remoteService.Transport(targetRemote.Type); err == nil && !transport.Capabilities().Force {
+				fmt.Printf("Note: %s remotes always overwrite, --force has no effect\n", targetRemote.Type)
+			}
+		}
+		
+		err = remoteService.PushRepository(*targetRemote, repo, force)
 		if err != nil {
 			fmt.Printf("Push failed: %v\n", err)
+			if remote.IsTransient(err) && force {
+				fmt.Printf("Forced pushes are not queued; run 'todo push %s --force' again once it is reachable\n", targetRemote.Name)
+			} else if remote.IsTransient(err) {
+				if err := queuePush(targetRemote.Name, err); err != nil {
+					fmt.Printf("Error queueing push: %v\n", err)
+					return
+				}
+				fmt.Println("Push queued; 'todo sync', 'todo pull' or 'todo fetch' will push the repository as it is then")
+			}
 			return
 		}
+		clearPendingPush(targetRemote.Name)
 		
 		fmt.Printf("Successfully pushed to %s\n", targetRemote.Name)
 	},
@@ -168,10 +391,17 @@ var PullCmd = &cobra.Command{
 		if len(args) > 0 {
 			remoteName = args[0]
 		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		output, _ := cmd.Flags().GetString("output")
+		if err := validateOutput(output); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
 		
 		repo, err := storage_instance.LoadRepository()
 		if err != nil {
-			fmt.Printf("Error loading repository: %v\n", err)
+			printError(output, "Error loading repository: %v", err)
 			return
 		}
 		
@@ -185,30 +415,52 @@ var PullCmd = &cobra.Command{
 		}
 		
 		if targetRemote == nil {
-			fmt.Printf("Remote '%s' not found\n", remoteName)
+			printError(output, "Remote '%s' not found", remoteName)
 			return
 		}
 		
-		fmt.Printf("Pulling from %s (%s)...\n", targetRemote.Name, targetRemote.URL)
+		if output == "text" {
+			fmt.Printf("Pulling from %s (%s)...\n", targetRemote.Name, targetRemote.URL)
+		}
 		
 		remoteRepo, err := remoteService.PullRepository(*targetRemote)
 		if err != nil {
-			fmt.Printf("Pull failed: %v\n", err)
+			printError(output, "Pull failed: %v", err)
+			return
+		}
+		
+		if err := storage_instance.SaveRemoteState(targetRemote.Name, remoteRepo); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		
+		if dryRun {
+			// Compare against a fresh copy since merging shares the local branch slice
+			localRepo, err := storage_instance.LoadRepository()
+			if err != nil {
+				printError(output, "Error loading repository: %v", err)
+				return
+			}
+			mergedRepo, _ := remoteService.MergeRepositories(repo, remoteRepo)
+			printDiff(output, diff.CompareRepositories("", localRepo, "", mergedRepo))
 			return
 		}
 		
 		// Merge remote changes
-		mergedRepo := remoteService.MergeRepositories(repo, remoteRepo)
+		mergedRepo, report := remoteService.MergeRepositories(repo, remoteRepo)
 		
 		err = storage_instance.SaveRepository(mergedRepo)
 		if err != nil {
-			fmt.Printf("Error saving merged repository: %v\n", err)
+			printError(output, "Error saving merged repository: %v", err)
 			return
 		}
+		if err := storage_instance.SaveSyncBase(targetRemote.Name, remoteRepo); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
 		
-		fmt.Printf("Successfully pulled and merged from %s\n", targetRemote.Name)
-		fmt.Printf("- %d branches synced\n", len(remoteRepo.Branches))
-		fmt.Printf("- %d commits synced\n", len(remoteRepo.Commits))
+		if output == "text" {
+			fmt.Printf("Successfully pulled and merged from %s\n", targetRemote.Name)
+		}
+		printMergeReport(report, verbose, output)
 	},
 }
 
@@ -222,9 +474,16 @@ var FetchCmd = &cobra.Command{
 			remoteName = args[0]
 		}
 		
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		output, _ := cmd.Flags().GetString("output")
+		if err := validateOutput(output); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		
 		repo, err := storage_instance.LoadRepository()
 		if err != nil {
-			fmt.Printf("Error loading repository: %v\n", err)
+			printError(output, "Error loading repository: %v", err)
 			return
 		}
 		
@@ -238,57 +497,65 @@ var FetchCmd = &cobra.Command{
 		}
 		
 		if targetRemote == nil {
-			fmt.Printf("Remote '%s' not found\n", remoteName)
+			printError(output, "Remote '%s' not found", remoteName)
 			return
 		}
 		
-		fmt.Printf("Fetching from %s (%s)...\n", targetRemote.Name, targetRemote.URL)
+		if output == "text" {
+			fmt.Printf("Fetching from %s (%s)...\n", targetRemote.Name, targetRemote.URL)
+		}
 		
-		remoteRepo, err := remoteService.PullRepository(*targetRemote)
+		remoteRepo, err := remoteService.FetchRepository(*targetRemote)
 		if err != nil {
-			fmt.Printf("Fetch failed: %v\n", err)
+			printError(output, "Fetch failed: %v", err)
 			return
 		}
 		
-		// Show what would be merged
-		fmt.Printf("Remote has:\n")
-		fmt.Printf("- %d branches\n", len(remoteRepo.Branches))
-		fmt.Printf("- %d commits\n", len(remoteRepo.Commits))
-		fmt.Printf("- Last sync: %s\n", remoteRepo.LastSync.Format("2006-01-02 15:04:05"))
-		fmt.Println("\nUse 'todo pull' to merge these changes")
-	},
-}
-
-var SyncCmd = &cobra.Command{
-	Use:   "sync [remote]",
-	Short: "Synchronize with remote (pull then push)",
-	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		remoteName := "origin"
-		if len(args) > 0 {
-			remoteName = args[0]
+		err = storage_instance.SaveRemoteState(targetRemote.Name, remoteRepo)
+		if err != nil {
+			printError(output, "Error saving remote state: %v", err)
+			return
 		}
 		
-		fmt.Printf("Synchronizing with %s...\n", remoteName)
-		
-		// First pull
-		PullCmd.Run(cmd, args)
-		
-		// Then push
-		PushCmd.Run(cmd, args)
-		
-		fmt.Printf("Synchronization with %s complete\n", remoteName)
+		// Show what would be merged; the merged copy is thrown away
+		_, report := remoteService.MergeRepositories(repo, remoteRepo)
+		if output == "json" {
+			printMergeReport(report, verbose, output)
+			return
+		}
+		fmt.Printf("Pulling from %s would bring in:\n", targetRemote.Name)
+		printMergeReport(report, verbose, output)
+		fmt.Println("\nUse 'todo diff <branch> " + targetRemote.Name + "/<branch>' to inspect changes")
+		fmt.Println("Use 'todo pull' to merge these changes")
 	},
 }
 
 func init() {
 	// Add flags
-	remoteAddCmd.Flags().StringP("type", "t", "http", "Remote type (http, file)")
+	for _, c := range []*cobra.Command{remoteAddCmd, remoteUpdateCmd} {
+		c.Flags().StringP("type", "t", "", "Remote type (http, file, git, s3, ssh or a todo-remote-<type> executable; inferred from the URL by default)")
+		c.Flags().String("credential-helper", "", "Credential helper executable for this remote")
+		c.Flags().String("ca-cert", "", "PEM bundle of CAs to trust for HTTPS")
+		c.Flags().String("client-cert", "", "PEM client certificate for mutual TLS")
+		c.Flags().String("client-key", "", "PEM client key for mutual TLS")
+		c.Flags().Bool("insecure-skip-verify", false, "Skip TLS certificate verification (testing only)")
+		c.Flags().Int("max-attempts", 0, "Attempts per push or pull when the remote is unreachable (default 4, 1 disables retries)")
+		c.Flags().String("timeout", "", "Timeout per request, e.g. 45s (default 30s)")
+	}
+	remoteUpdateCmd.Flags().String("url", "", "New remote URL")
+	PushCmd.Flags().BoolP("force", "f", false, "Overwrite remote changes missing locally (admin only on servers)")
+	PushCmd.Flags().Bool("no-verify", false, "Skip the pre-push hook")
+	PullCmd.Flags().Bool("dry-run", false, "Show what the pull would change without applying it")
+	for _, c := range []*cobra.Command{PullCmd, FetchCmd} {
+		c.Flags().BoolP("verbose", "v", false, "List every new branch, todo, changed field and commit")
+		c.Flags().StringP("output", "o", "text", "Report format (text, json); with --dry-run the format of the diff")
+	}
 	
 	// Add subcommands
 	RemoteCmd.AddCommand(remoteAddCmd)
 	RemoteCmd.AddCommand(remoteListCmd)
 	RemoteCmd.AddCommand(remoteRemoveCmd)
+	RemoteCmd.AddCommand(remoteUpdateCmd)
 	
 	// Add standalone commands that will be added to root
 }
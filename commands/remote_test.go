@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"todo-cli/models"
+)
+
+func TestRemoteUpdateURLInfersType(t *testing.T) {
+	useTempHome(t)
+	saveTestRepository(t, &models.Repository{
+		Branches:      []models.Branch{{Name: "main", IsActive: true}},
+		CurrentBranch: "main",
+		Remotes:       []models.Remote{{Name: "origin", URL: "https://todo.example.com", Type: "http"}},
+	})
+
+	setFlags(t, remoteUpdateCmd, map[string]string{"url": "s3://bucket/todos"})
+	remoteUpdateCmd.Run(remoteUpdateCmd, []string{"origin"})
+
+	if got := loadTestRepository(t).Remotes[0]; got.URL != "s3://bucket/todos" || got.Type != "s3" {
+		t.Errorf("remote = %+v, want an s3 remote", got)
+	}
+}
+
+func TestRemoteUpdateRejectsUnknownType(t *testing.T) {
+	useTempHome(t)
+	original := models.Remote{Name: "origin", URL: "https://todo.example.com", Type: "http"}
+	saveTestRepository(t, &models.Repository{
+		Branches:      []models.Branch{{Name: "main", IsActive: true}},
+		CurrentBranch: "main",
+		Remotes:       []models.Remote{original},
+	})
+
+	setFlags(t, remoteUpdateCmd, map[string]string{"url": "nosuchscheme://host/todos"})
+	remoteUpdateCmd.Run(remoteUpdateCmd, []string{"origin"})
+
+	if got := loadTestRepository(t).Remotes[0]; got != original {
+		t.Errorf("remote = %+v, want it unchanged", got)
+	}
+}
+
+func TestRemoteUpdateTypeIsLowercased(t *testing.T) {
+	useTempHome(t)
+	saveTestRepository(t, &models.Repository{
+		Branches:      []models.Branch{{Name: "main", IsActive: true}},
+		CurrentBranch: "main",
+		Remotes:       []models.Remote{{Name: "origin", URL: "/srv/todos.json", Type: "file"}},
+	})
+
+	setFlags(t, remoteUpdateCmd, map[string]string{"url": "https://todo.example.com/x.git", "type": "HTTP"})
+	remoteUpdateCmd.Run(remoteUpdateCmd, []string{"origin"})
+
+	if got := loadTestRepository(t).Remotes[0]; got.Type != "http" {
+		t.Errorf("remote type = %q, want http", got.Type)
+	}
+}
+
+func TestPullDryRunJSON(t *testing.T) {
+	useTempHome(t)
+	remoteFile := filepath.Join(t.TempDir(), "remote.json")
+	saveTestRepository(t, &models.Repository{
+		Branches:      []models.Branch{{Name: "main", IsActive: true}},
+		CurrentBranch: "main",
+		NextTodoID:    1,
+		Remotes:       []models.Remote{{Name: "origin", URL: remoteFile, Type: "file"}},
+	})
+	data, err := json.Marshal(&models.Repository{
+		Branches:   []models.Branch{{Name: "main", Todos: []models.Todo{{ID: 1, Title: "From remote", Status: "pending"}}}},
+		NextTodoID: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, remoteFile, string(data))
+
+	setFlags(t, PullCmd, map[string]string{"dry-run": "true", "output": "json"})
+	output := captureStdout(t, func() { PullCmd.Run(PullCmd, nil) })
+
+	var results []map[string]interface{}
+	if err := json.Unmarshal([]byte(output), &results); err != nil || len(results) == 0 {
+		t.Fatalf("output is not a JSON diff (%v):\n%s", err, output)
+	}
+	if todos := loadTestRepository(t).Branches[0].Todos; len(todos) != 0 {
+		t.Errorf("dry run merged todos %+v", todos)
+	}
+}
+
+func TestPullReportsErrorsAsJSON(t *testing.T) {
+	useTempHome(t)
+	saveTestRepository(t, &models.Repository{
+		Branches:      []models.Branch{{Name: "main", IsActive: true}},
+		CurrentBranch: "main",
+	})
+
+	setFlags(t, PullCmd, map[string]string{"output": "json"})
+	output := captureStdout(t, func() { PullCmd.Run(PullCmd, []string{"nowhere"}) })
+
+	var result map[string]string
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("output is not JSON (%v):\n%s", err, output)
+	}
+	if want := "Remote 'nowhere' not found"; result["error"] != want {
+		t.Errorf("error = %q, want %q", result["error"], want)
+	}
+}
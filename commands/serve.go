@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var ServeStdioCmd = &cobra.Command{
+	Use:   "serve-stdio <path>",
+	Short: "Answer one push or pull for a repository file over stdin/stdout",
+	Long: `serve-stdio is run on the remote host by ssh:// remotes. It reads one
+JSON request from stdin, applies it to the repository file at <path> and
+writes the JSON response to stdout. It is not meant to be run by hand.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := remoteService.ServeStdio(args[0], os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"todo-cli/models"
+
+	"github.com/spf13/cobra"
+)
+
+var StageCmd = &cobra.Command{
+	Use:   "stage [id...]",
+	Short: "Stage completed todos for the next commit",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		currentBranch := storage_instance.GetCurrentBranch(repo)
+		if currentBranch == nil {
+			fmt.Println("No current branch found")
+			return
+		}
+
+		// Only completed todos that changed since the last commit can be staged
+		uncommitted := make(map[int]bool)
+		for _, todo := range storage_instance.GetUncommittedTodos(repo, currentBranch.Name) {
+			uncommitted[todo.ID] = true
+		}
+
+		staged := 0
+		for _, arg := range args {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				fmt.Printf("Invalid todo ID: %s\n", arg)
+				continue
+			}
+
+			todo := storage_instance.GetTodoByID(currentBranch, id)
+			if todo == nil {
+				fmt.Printf("Todo #%d not found in current branch\n", id)
+				continue
+			}
+			if todo.Status != "completed" {
+				fmt.Printf("Todo #%d is not completed\n", id)
+				continue
+			}
+			if !uncommitted[id] {
+				fmt.Printf("Todo #%d has no changes since the last commit\n", id)
+				continue
+			}
+			if isStaged(currentBranch, id) {
+				fmt.Printf("Todo #%d is already staged\n", id)
+				continue
+			}
+
+			currentBranch.Staged = append(currentBranch.Staged, id)
+			staged++
+		}
+
+		if staged == 0 {
+			return
+		}
+
+		err = storage_instance.SaveRepository(repo)
+		if err != nil {
+			fmt.Printf("Error saving repository: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Staged %d todos\n", staged)
+	},
+}
+
+var UnstageCmd = &cobra.Command{
+	Use:   "unstage [id...]",
+	Short: "Remove todos from the staging area (all if no IDs are given)",
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		currentBranch := storage_instance.GetCurrentBranch(repo)
+		if currentBranch == nil {
+			fmt.Println("No current branch found")
+			return
+		}
+
+		unstaged := 0
+		if len(args) == 0 {
+			unstaged = len(currentBranch.Staged)
+			currentBranch.Staged = nil
+		} else {
+			for _, arg := range args {
+				id, err := strconv.Atoi(arg)
+				if err != nil {
+					fmt.Printf("Invalid todo ID: %s\n", arg)
+					continue
+				}
+
+				if !isStaged(currentBranch, id) {
+					fmt.Printf("Todo #%d is not staged\n", id)
+					continue
+				}
+
+				for i, stagedID := range currentBranch.Staged {
+					if stagedID == id {
+						currentBranch.Staged = append(currentBranch.Staged[:i], currentBranch.Staged[i+1:]...)
+						break
+					}
+				}
+				unstaged++
+			}
+		}
+
+		err = storage_instance.SaveRepository(repo)
+		if err != nil {
+			fmt.Printf("Error saving repository: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Unstaged %d todos\n", unstaged)
+	},
+}
+
+// isStaged reports whether a todo is in the branch's staging area
+func isStaged(branch *models.Branch, id int) bool {
+	for _, stagedID := range branch.Staged {
+		if stagedID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,421 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"todo-cli/diff"
+	"todo-cli/models"
+	"todo-cli/remote"
+	"todo-cli/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var SyncCmd = &cobra.Command{
+	Use:   "sync [remote]",
+	Short: "Synchronize with remote (fetch, merge, push)",
+	Long: `Sync fetches the remote, merges it into the local repository and pushes the
+result. Nothing is pushed if fetching or merging fails.
+
+A todo changed both locally and on the remote since the last sync, or deleted
+on one side and changed on the other, is a conflict: sync stops without changing anything unless --strategy says which
+side wins. So are two different todos added on each side under the same ID,
+which happens when two machines add todos offline.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		strategy, _ := cmd.Flags().GetString("strategy")
+		noVerify, _ := cmd.Flags().GetBool("no-verify")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		output, _ := cmd.Flags().GetString("output")
+		if err := validateOutput(output); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		if strategy != "" && strategy != "ours" && strategy != "theirs" {
+			printError(output, "Invalid strategy '%s' (valid: ours, theirs)", strategy)
+			return
+		}
+		if all && len(args) > 0 {
+			printError(output, "Give either a remote or --all, not both")
+			return
+		}
+
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			printError(output, "Error loading repository: %v", err)
+			return
+		}
+
+		var names []string
+		if all {
+			for _, r := range repo.Remotes {
+				names = append(names, r.Name)
+			}
+			if len(names) == 0 {
+				printError(output, "No remotes configured")
+				return
+			}
+		} else {
+			name := "origin"
+			if len(args) > 0 {
+				name = args[0]
+			}
+			if storage_instance.GetRemoteByName(repo, name) == nil {
+				printError(output, "Remote '%s' not found", name)
+				return
+			}
+			names = []string{name}
+		}
+
+		jsonOutput := output == "json"
+		synced := 0
+		results := []*syncResult{}
+		for _, name := range names {
+			if !jsonOutput {
+				fmt.Printf("Synchronizing with %s...\n", name)
+			}
+			result, err := syncRemote(name, strategy, noVerify, jsonOutput)
+			if err != nil {
+				if !jsonOutput {
+					fmt.Printf("Sync with %s failed: %v\n", name, err)
+				}
+				results = append(results, &syncResult{Remote: name, Error: err.Error()})
+				continue
+			}
+			if !jsonOutput {
+				result.print(verbose)
+			}
+			results = append(results, result)
+			synced++
+		}
+
+		// Deliver pushes queued for remotes that were not synced; a remote
+		// synced above was just pushed to, or queued because it failed. This
+		// reports on stderr, so it does not mix with the JSON report
+		skip := make(map[string]bool)
+		for _, name := range names {
+			skip[name] = true
+		}
+		flushPendingPushes(false, noVerify, skip)
+
+		if jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(results); err != nil {
+				fmt.Printf("Error writing report: %v\n", err)
+			}
+			return
+		}
+
+		if len(names) > 1 {
+			fmt.Printf("Synchronized %d of %d remotes\n", synced, len(names))
+		}
+	},
+}
+
+// syncConflict is a todo changed differently on both sides since the last
+// sync, deleted on one side and changed on the other, or two different todos
+// added on each side under the same ID. A nil side was deleted.
+type syncConflict struct {
+	Branch string
+	ID     int
+	Local  *models.Todo
+	Remote *models.Todo
+	Added  bool // Both todos are new since the last sync
+}
+
+// syncResult summarizes one sync in both directions
+type syncResult struct {
+	Remote        string              `json:"remote"`
+	Pulled        *remote.MergeReport `json:"pulled,omitempty"` // What the merge brought in from the remote
+	Pushed        []diff.Result       `json:"pushed,omitempty"` // How the remote changed by the push
+	PushedCommits int                 `json:"pushed_commits"`
+	Resolved      int                 `json:"conflicts_resolved,omitempty"`
+	Strategy      string              `json:"strategy,omitempty"`
+	Error         string              `json:"error,omitempty"`
+}
+
+// syncRemote fetches one remote, merges it and pushes the merge. The local
+// repository is only saved once the merge succeeded, and the push only
+// happens after that.
+func syncRemote(name, strategy string, noVerify, quiet bool) (*syncResult, error) {
+	repo, err := storage_instance.LoadRepository()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load repository: %w", err)
+	}
+	target := storage_instance.GetRemoteByName(repo, name)
+	if target == nil {
+		return nil, fmt.Errorf("remote '%s' not found", name)
+	}
+	r := *target
+
+	remoteRepo, err := remoteService.PullRepository(r)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+
+	// The remote as of the last pull or sync is the merge base; fetch does
+	// not move it, so changes it brought in still count as remote changes
+	base, _ := storage_instance.LoadSyncBase(name)
+	conflicts := findSyncConflicts(base, repo, remoteRepo)
+	if len(conflicts) > 0 && strategy == "" {
+		for _, c := range conflicts {
+			if quiet {
+				break
+			}
+			how := "changed on both sides"
+			if c.Added {
+				how = "added on both sides with the same ID"
+			}
+			fmt.Printf("- conflict: todo #%d on '%s' %s (local: %s; remote: %s)\n",
+				c.ID, c.Branch, how, conflictSide(c.Local), conflictSide(c.Remote))
+		}
+		return nil, fmt.Errorf("%d conflicts, nothing was changed; rerun with --strategy ours or --strategy theirs", len(conflicts))
+	}
+
+	merged, report := remoteService.MergeRepositories(repo, remoteRepo)
+	resolveSyncConflicts(merged, conflicts, strategy)
+
+	result := &syncResult{
+		Remote:        name,
+		Pulled:        report,
+		Pushed:        diff.CompareRepositories("", remoteRepo, "", merged),
+		PushedCommits: missingCommitCount(remoteRepo, merged),
+		Resolved:      len(conflicts),
+		Strategy:      strategy,
+	}
+
+	if err := storage_instance.SaveRepository(merged); err != nil {
+		return nil, fmt.Errorf("failed to save merged repository: %w", err)
+	}
+	if err := storage_instance.SaveRemoteState(name, remoteRepo); err != nil && !quiet {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if err := storage_instance.SaveSyncBase(name, remoteRepo); err != nil && !quiet {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	if !noVerify {
+		err = runHook(hookPrePush, map[string]interface{}{
+			"remote":   r.Name,
+			"url":      r.URL,
+			"force":    false,
+			"branch":   merged.CurrentBranch,
+			"branches": len(merged.Branches),
+			"commits":  merged.Commits,
+			"queued":   false,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("merged locally, push aborted: %w", err)
+		}
+	}
+
+	if err := remoteService.PushRepository(r, merged, false); err != nil {
+		if remote.IsTransient(err) {
+			if qerr := queuePush(name, err); qerr == nil {
+				return nil, fmt.Errorf("merged locally, push queued: %w", err)
+			}
+		}
+		return nil, fmt.Errorf("merged locally, push: %w", err)
+	}
+	clearPendingPush(name)
+
+	// The remote now holds the merged state, which is the next merge base
+	if err := storage_instance.SaveRemoteState(name, merged); err != nil && !quiet {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if err := storage_instance.SaveSyncBase(name, merged); err != nil && !quiet {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	return result, nil
+}
+
+// findSyncConflicts returns todos changed both locally and on the remote since
+// base, where the two versions differ, todos deleted on one side but changed
+// on the other, and different todos each side added under the same ID since
+// base, as happens when two machines add todos offline. Without a base
+// nothing can be a conflict.
+func findSyncConflicts(base, local, remoteRepo *models.Repository) []syncConflict {
+	if base == nil {
+		return nil
+	}
+
+	var conflicts []syncConflict
+	for _, localBranch := range local.Branches {
+		remoteBranch := storage_instance.GetBranchByName(remoteRepo, localBranch.Name)
+		if remoteBranch == nil {
+			continue
+		}
+		baseBranch := storage_instance.GetBranchByName(base, localBranch.Name)
+		if baseBranch == nil {
+			baseBranch = &models.Branch{Name: localBranch.Name}
+		}
+
+		for _, localTodo := range localBranch.Todos {
+			if storage_instance.GetTodoByID(baseBranch, localTodo.ID) != nil {
+				continue
+			}
+			remoteTodo := storage_instance.GetTodoByID(remoteBranch, localTodo.ID)
+			if remoteTodo != nil && storage.TodoChanged(localTodo, *remoteTodo) {
+				conflicts = append(conflicts, syncConflict{Branch: localBranch.Name, ID: localTodo.ID, Local: copyTodo(&localTodo), Remote: copyTodo(remoteTodo), Added: true})
+			}
+		}
+
+		for _, baseTodo := range baseBranch.Todos {
+			localTodo := storage_instance.GetTodoByID(&localBranch, baseTodo.ID)
+			remoteTodo := storage_instance.GetTodoByID(remoteBranch, baseTodo.ID)
+
+			var conflict bool
+			switch {
+			case localTodo == nil && remoteTodo == nil:
+				// Deleted on both sides
+			case localTodo == nil:
+				conflict = storage.TodoChanged(baseTodo, *remoteTodo)
+			case remoteTodo == nil:
+				conflict = storage.TodoChanged(baseTodo, *localTodo)
+			default:
+				conflict = storage.TodoChanged(baseTodo, *localTodo) && storage.TodoChanged(baseTodo, *remoteTodo) && storage.TodoChanged(*localTodo, *remoteTodo)
+			}
+			if conflict {
+				conflicts = append(conflicts, syncConflict{Branch: localBranch.Name, ID: baseTodo.ID, Local: copyTodo(localTodo), Remote: copyTodo(remoteTodo)})
+			}
+		}
+	}
+	return conflicts
+}
+
+// resolveSyncConflicts applies the chosen side of each conflict to merged,
+// removing todos whose chosen side deleted them
+func resolveSyncConflicts(merged *models.Repository, conflicts []syncConflict, strategy string) {
+	for _, c := range conflicts {
+		branch := storage_instance.GetBranchByName(merged, c.Branch)
+		if branch == nil {
+			continue
+		}
+
+		chosen := c.Local
+		if strategy == "theirs" {
+			chosen = c.Remote
+		}
+
+		todos := make([]models.Todo, 0, len(branch.Todos))
+		found := false
+		for _, todo := range branch.Todos {
+			if todo.ID != c.ID {
+				todos = append(todos, todo)
+				continue
+			}
+			found = true
+			if chosen != nil {
+				todos = append(todos, *chosen)
+			}
+		}
+		if !found && chosen != nil {
+			todos = append(todos, *chosen)
+		}
+		branch.Todos = todos
+	}
+}
+
+// copyTodo returns a pointer to a copy of todo, or nil for nil, so conflicts
+// do not change when the repositories they came from are merged
+func copyTodo(todo *models.Todo) *models.Todo {
+	if todo == nil {
+		return nil
+	}
+	copied := *todo
+	return &copied
+}
+
+// conflictSide describes one side of a sync conflict
+func conflictSide(todo *models.Todo) string {
+	if todo == nil {
+		return "deleted"
+	}
+	return fmt.Sprintf("%s, %s", todo.Title, todo.Status)
+}
+
+// missingCommitCount counts commits of repo that other does not have
+func missingCommitCount(other, repo *models.Repository) int {
+	known := make(map[string]bool)
+	for _, commit := range other.Commits {
+		known[commit.ID] = true
+	}
+	missing := 0
+	for _, commit := range repo.Commits {
+		if !known[commit.ID] {
+			missing++
+		}
+	}
+	return missing
+}
+
+// print writes the combined summary of a sync
+func (s *syncResult) print(verbose bool) {
+	fmt.Printf("Synchronized with %s\n", s.Remote)
+	fmt.Println("Pulled:")
+	s.Pulled.WriteText(os.Stdout, verbose)
+	fmt.Println("Pushed:")
+	fmt.Printf("- %s, %d commits\n", summarizeChanges(s.Pushed), s.PushedCommits)
+	if verbose {
+		for _, result := range s.Pushed {
+			for _, change := range result.Changes {
+				fmt.Printf("    %s #%d [%s] %s\n", changeMarker(change.Type), change.ID, result.To, change.Title)
+				for _, field := range change.Fields {
+					fmt.Printf("        %s: %s → %s\n", field.Field, field.Before, field.After)
+				}
+			}
+		}
+	}
+	if s.Resolved > 0 {
+		side := "local"
+		if s.Strategy == "theirs" {
+			side = "remote"
+		}
+		fmt.Printf("- %d conflicts resolved with the %s version\n", s.Resolved, side)
+	}
+}
+
+func changeMarker(changeType string) string {
+	switch changeType {
+	case diff.Added:
+		return "+"
+	case diff.Removed:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// summarizeChanges counts added, modified and removed todos across branches
+func summarizeChanges(results []diff.Result) string {
+	var added, modified, removed int
+	for _, result := range results {
+		for _, change := range result.Changes {
+			switch change.Type {
+			case diff.Added:
+				added++
+			case diff.Modified:
+				modified++
+			case diff.Removed:
+				removed++
+			}
+		}
+	}
+	if added+modified+removed == 0 {
+		return "no todo changes"
+	}
+	return fmt.Sprintf("%d added, %d modified, %d removed todos", added, modified, removed)
+}
+
+func init() {
+	SyncCmd.Flags().Bool("all", false, "Synchronize with every configured remote")
+	SyncCmd.Flags().String("strategy", "", "Resolve conflicts with the local (ours) or remote (theirs) version")
+	SyncCmd.Flags().Bool("no-verify", false, "Skip the pre-push hook")
+	SyncCmd.Flags().BoolP("verbose", "v", false, "List every new branch, todo, changed field and commit")
+	SyncCmd.Flags().StringP("output", "o", "text", "Report format (text, json)")
+}
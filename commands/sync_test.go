@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"todo-cli/models"
+)
+
+// syncTestRepository returns a repository whose main branch has the todos
+func syncTestRepository(todos ...models.Todo) *models.Repository {
+	return &models.Repository{
+		Branches:      []models.Branch{{Name: "main", IsActive: true, Todos: todos}},
+		CurrentBranch: "main",
+		NextTodoID:    10,
+	}
+}
+
+func syncTestTodo(id int, title, status string) models.Todo {
+	return models.Todo{ID: id, Title: title, Status: status, Priority: "medium", BranchName: "main"}
+}
+
+func TestFindSyncConflicts(t *testing.T) {
+	useTempHome(t)
+	base := syncTestRepository(
+		syncTestTodo(1, "Edited on both sides", "pending"),
+		syncTestTodo(2, "Edited the same way", "pending"),
+		syncTestTodo(3, "Deleted locally, edited remotely", "pending"),
+		syncTestTodo(4, "Edited locally, deleted remotely", "pending"),
+		syncTestTodo(5, "Deleted locally, unchanged remotely", "pending"),
+		syncTestTodo(6, "Deleted on both sides", "pending"),
+		syncTestTodo(7, "Edited locally only", "pending"),
+	)
+	local := syncTestRepository(
+		syncTestTodo(1, "Edited on both sides", "completed"),
+		syncTestTodo(2, "Edited the same way", "completed"),
+		syncTestTodo(4, "Edited locally, deleted remotely", "in-progress"),
+		syncTestTodo(7, "Edited locally only", "completed"),
+		syncTestTodo(10, "Added locally", "pending"),
+		syncTestTodo(11, "Added the same on both sides", "pending"),
+	)
+	remoteRepo := syncTestRepository(
+		syncTestTodo(1, "Edited on both sides", "in-progress"),
+		syncTestTodo(2, "Edited the same way", "completed"),
+		syncTestTodo(3, "Deleted locally, edited remotely", "completed"),
+		syncTestTodo(5, "Deleted locally, unchanged remotely", "pending"),
+		syncTestTodo(7, "Edited locally only", "pending"),
+		syncTestTodo(10, "Added remotely", "pending"),
+		syncTestTodo(11, "Added the same on both sides", "pending"),
+	)
+
+	conflicts := findSyncConflicts(base, local, remoteRepo)
+	if len(conflicts) != 4 {
+		t.Fatalf("conflicts = %+v, want todos 10, 1, 3 and 4", conflicts)
+	}
+	if c := conflicts[0]; c.ID != 10 || !c.Added || c.Local.Title != "Added locally" || c.Remote.Title != "Added remotely" {
+		t.Errorf("conflict = %+v, want #10 added on both sides", c)
+	}
+	if c := conflicts[1]; c.ID != 1 || c.Local == nil || c.Remote == nil || c.Added {
+		t.Errorf("conflict = %+v, want #1 edited on both sides", c)
+	}
+	if c := conflicts[2]; c.ID != 3 || c.Local != nil || c.Remote == nil || c.Remote.Status != "completed" {
+		t.Errorf("conflict = %+v, want #3 deleted locally", c)
+	}
+	if c := conflicts[3]; c.ID != 4 || c.Local == nil || c.Remote != nil {
+		t.Errorf("conflict = %+v, want #4 deleted remotely", c)
+	}
+
+	if conflicts := findSyncConflicts(nil, local, remoteRepo); conflicts != nil {
+		t.Errorf("conflicts without a base = %+v", conflicts)
+	}
+}
+
+func TestResolveSyncConflicts(t *testing.T) {
+	useTempHome(t)
+	edited := syncTestTodo(3, "Edited remotely", "completed")
+	local := syncTestTodo(4, "Edited locally", "in-progress")
+	conflicts := []syncConflict{
+		{Branch: "main", ID: 3, Remote: &edited},
+		{Branch: "main", ID: 4, Local: &local},
+	}
+
+	// The merge keeps todos that exist on either side
+	merged := syncTestRepository(edited, local)
+	resolveSyncConflicts(merged, conflicts, "ours")
+	if todos := merged.Branches[0].Todos; len(todos) != 1 || todos[0].ID != 4 {
+		t.Errorf("ours = %+v, want only the local todo #4", todos)
+	}
+
+	merged = syncTestRepository(edited, local)
+	resolveSyncConflicts(merged, conflicts, "theirs")
+	if todos := merged.Branches[0].Todos; len(todos) != 1 || todos[0].ID != 3 {
+		t.Errorf("theirs = %+v, want only the remote todo #3", todos)
+	}
+}
+
+func TestSyncStopsOnDeleteEditConflict(t *testing.T) {
+	useTempHome(t)
+	target := filepath.Join(t.TempDir(), "remote.json")
+	base := syncTestRepository(syncTestTodo(1, "Shared", "pending"))
+
+	local := syncTestRepository()
+	local.Remotes = []models.Remote{{Name: "backup", URL: target, Type: "file"}}
+	saveTestRepository(t, local)
+	if err := storage_instance.SaveSyncBase("backup", base); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(syncTestRepository(syncTestTodo(1, "Shared", "completed")))
+	writeFile(t, target, string(data))
+
+	if _, err := syncRemote("backup", "", true, true); err == nil {
+		t.Fatal("sync succeeded despite a delete/edit conflict")
+	}
+	if todos := loadTestRepository(t).Branches[0].Todos; len(todos) != 0 {
+		t.Errorf("local todos = %+v, want nothing changed", todos)
+	}
+
+	if _, err := syncRemote("backup", "ours", true, true); err != nil {
+		t.Fatal(err)
+	}
+	if todos := loadTestRepository(t).Branches[0].Todos; len(todos) != 0 {
+		t.Errorf("local todos after ours = %+v, want the deletion kept", todos)
+	}
+	pushed, _ := os.ReadFile(target)
+	var remoteRepo models.Repository
+	json.Unmarshal(pushed, &remoteRepo)
+	if todos := remoteRepo.Branches[0].Todos; len(todos) != 0 {
+		t.Errorf("remote todos after ours = %+v, want the deletion pushed", todos)
+	}
+}
+
+func TestSyncDetectsConflictAfterFetch(t *testing.T) {
+	useTempHome(t)
+	target := filepath.Join(t.TempDir(), "remote.json")
+	writeRemote := func(repo *models.Repository) {
+		data, _ := json.Marshal(repo)
+		writeFile(t, target, string(data))
+	}
+
+	local := syncTestRepository(syncTestTodo(1, "Shared", "pending"))
+	local.Remotes = []models.Remote{{Name: "backup", URL: target, Type: "file"}}
+	saveTestRepository(t, local)
+	writeRemote(syncTestRepository(syncTestTodo(1, "Shared", "pending")))
+	if _, err := syncRemote("backup", "", true, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both sides change the todo; fetching the remote change must not make
+	// it part of the merge base
+	writeRemote(syncTestRepository(syncTestTodo(1, "Shared", "completed")))
+	repo := loadTestRepository(t)
+	repo.Branches[0].Todos[0].Status = "in-progress"
+	saveTestRepository(t, repo)
+	captureStdout(t, func() { FetchCmd.Run(FetchCmd, []string{"backup"}) })
+
+	if _, err := syncRemote("backup", "", true, true); err == nil {
+		t.Fatal("sync after fetch merged a todo changed on both sides")
+	}
+	if todo := loadTestRepository(t).Branches[0].Todos[0]; todo.Status != "in-progress" {
+		t.Errorf("local todo = %+v, want it unchanged", todo)
+	}
+}
+
+func TestSyncJSONFlushesQueuedPushes(t *testing.T) {
+	target := queueTestSetup(t)
+	repo := loadTestRepository(t)
+	repo.Remotes = append(repo.Remotes, models.Remote{Name: "other", URL: filepath.Join(t.TempDir(), "missing.json"), Type: "file"})
+	saveTestRepository(t, repo)
+
+	setFlags(t, SyncCmd, map[string]string{"output": "json", "no-verify": "true"})
+	output := captureStdout(t, func() { SyncCmd.Run(SyncCmd, []string{"other"}) })
+
+	var results []syncResult
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatalf("output is not a JSON report (%v):\n%s", err, output)
+	}
+	if _, err := os.Stat(target); err != nil {
+		t.Errorf("push queued for backup was not delivered: %v", err)
+	}
+}
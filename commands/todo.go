@@ -23,6 +23,11 @@ var todoAddCmd = &cobra.Command{
         description, _ := cmd.Flags().GetString("description")
         priority, _ := cmd.Flags().GetString("priority")
         
+        if !models.ValidPriority(priority) {
+            fmt.Println("Priority must be: low, medium, or high")
+            return
+        }
+        
         repo, err := storage_instance.LoadRepository()
         if err != nil {
             fmt.Printf("Error loading repository: %v\n", err)
@@ -113,7 +118,7 @@ var todoUpdateCmd = &cobra.Command{
         }
         
         status := args[1]
-        if status != "pending" && status != "in-progress" && status != "completed" {
+        if !models.ValidStatus(status) {
             fmt.Println("Status must be: pending, in-progress, or completed")
             return
         }
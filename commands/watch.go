@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+	"todo-cli/models"
+	"todo-cli/remote"
+
+	"github.com/spf13/cobra"
+)
+
+const watchRetryDelay = 5 * time.Second
+
+var WatchCmd = &cobra.Command{
+	Use:   "watch [remote]",
+	Short: "Watch a remote for pushes and optionally fetch or pull them",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		remoteName := "origin"
+		if len(args) > 0 {
+			remoteName = args[0]
+		}
+		fetch, _ := cmd.Flags().GetBool("fetch")
+		pull, _ := cmd.Flags().GetBool("pull")
+
+		repo, err := storage_instance.LoadRepository()
+		if err != nil {
+			fmt.Printf("Error loading repository: %v\n", err)
+			return
+		}
+
+		targetRemote := storage_instance.GetRemoteByName(repo, remoteName)
+		if targetRemote == nil {
+			fmt.Printf("Remote '%s' not found\n", remoteName)
+			return
+		}
+
+		fmt.Printf("Watching %s (%s), press Ctrl+C to stop...\n", targetRemote.Name, targetRemote.URL)
+
+		// Reconnect after transient failures until interrupted, give up on
+		// errors retrying cannot fix such as denied access or an unknown repository
+		for {
+			err := remoteService.WatchEvents(*targetRemote, func(event models.Event) error {
+				printEvent(event)
+				switch {
+				case pull:
+					watchPull(*targetRemote)
+				case fetch:
+					watchFetch(*targetRemote)
+				}
+				return nil
+			})
+			if !remote.IsTransient(err) {
+				fmt.Printf("Watch failed: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Watch interrupted: %v, reconnecting in %s\n", err, watchRetryDelay)
+			time.Sleep(watchRetryDelay)
+		}
+	},
+}
+
+// printEvent prints a one-line summary of an event followed by its new commits
+func printEvent(event models.Event) {
+	by := ""
+	if event.User != "" {
+		by = " by " + event.User
+	}
+
+	fmt.Printf("[%s] %s to %s%s: %d branches, %d new commits\n",
+		event.Time.Local().Format("15:04:05"), event.Type, event.Repository, by, len(event.Branches), len(event.Commits))
+	for _, commit := range event.Commits {
+		fmt.Printf("  %s [%s] %s by %s\n", commit.ID, commit.Branch, commit.Message, commit.Author)
+	}
+}
+
+// watchFetch stores the latest remote state for remote-tracking refs
+func watchFetch(targetRemote models.Remote) {
+	remoteRepo, err := remoteService.PullRepository(targetRemote)
+	if err != nil {
+		fmt.Printf("  Fetch failed: %v\n", err)
+		return
+	}
+
+	err = storage_instance.SaveRemoteState(targetRemote.Name, remoteRepo)
+	if err != nil {
+		fmt.Printf("  Error saving remote state: %v\n", err)
+		return
+	}
+
+	fmt.Printf("  Fetched %s\n", targetRemote.Name)
+}
+
+// watchPull merges the latest remote state into the local repository
+func watchPull(targetRemote models.Remote) {
+	remoteRepo, err := remoteService.PullRepository(targetRemote)
+	if err != nil {
+		fmt.Printf("  Pull failed: %v\n", err)
+		return
+	}
+
+	// Reload so changes made while watching are not overwritten
+	repo, err := storage_instance.LoadRepository()
+	if err != nil {
+		fmt.Printf("  Error loading repository: %v\n", err)
+		return
+	}
+
+	err = storage_instance.SaveRemoteState(targetRemote.Name, remoteRepo)
+	if err != nil {
+		fmt.Printf("  Error saving remote state: %v\n", err)
+	}
+
+	merged, _ := remoteService.MergeRepositories(repo, remoteRepo)
+	err = storage_instance.SaveRepository(merged)
+	if err != nil {
+		fmt.Printf("  Error saving merged repository: %v\n", err)
+		return
+	}
+	err = storage_instance.SaveSyncBase(targetRemote.Name, remoteRepo)
+	if err != nil {
+		fmt.Printf("  Error saving sync base: %v\n", err)
+	}
+
+	fmt.Printf("  Pulled and merged from %s\n", targetRemote.Name)
+}
+
+func init() {
+	WatchCmd.Flags().Bool("fetch", false, "Fetch the remote state after each push")
+	WatchCmd.Flags().Bool("pull", false, "Pull and merge after each push")
+}
@@ -0,0 +1,183 @@
+// Package diff compares todos between branches, commits and remote state
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"todo-cli/models"
+)
+
+// Change types
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Modified = "modified"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+// FieldChange describes a single changed field of a todo
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// TodoChange describes how a todo differs between two sides
+type TodoChange struct {
+	Type   string        `json:"type"`
+	ID     int           `json:"id"`
+	Title  string        `json:"title"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// Result holds all todo changes between two sides
+type Result struct {
+	From    string       `json:"from"`
+	To      string       `json:"to"`
+	Changes []TodoChange `json:"changes"`
+}
+
+// Empty reports whether the two sides are identical
+func (r Result) Empty() bool {
+	return len(r.Changes) == 0
+}
+
+// FieldChanges returns the user-visible fields that differ between two todos
+func FieldChanges(before, after models.Todo) []FieldChange {
+	var fields []FieldChange
+	add := func(field, b, a string) {
+		if b != a {
+			fields = append(fields, FieldChange{Field: field, Before: b, After: a})
+		}
+	}
+
+	add("title", before.Title, after.Title)
+	add("description", before.Description, after.Description)
+	add("status", before.Status, after.Status)
+	add("priority", before.Priority, after.Priority)
+
+	return fields
+}
+
+// Compare diffs two todo lists, matching todos by ID
+func Compare(fromName string, from []models.Todo, toName string, to []models.Todo) Result {
+	result := Result{From: fromName, To: toName, Changes: []TodoChange{}}
+
+	fromMap := make(map[int]models.Todo)
+	for _, todo := range from {
+		fromMap[todo.ID] = todo
+	}
+
+	toMap := make(map[int]models.Todo)
+	for _, todo := range to {
+		toMap[todo.ID] = todo
+
+		before, ok := fromMap[todo.ID]
+		if !ok {
+			result.Changes = append(result.Changes, TodoChange{Type: Added, ID: todo.ID, Title: todo.Title})
+			continue
+		}
+
+		if fields := FieldChanges(before, todo); len(fields) > 0 {
+			result.Changes = append(result.Changes, TodoChange{Type: Modified, ID: todo.ID, Title: todo.Title, Fields: fields})
+		}
+	}
+
+	for _, todo := range from {
+		if _, ok := toMap[todo.ID]; !ok {
+			result.Changes = append(result.Changes, TodoChange{Type: Removed, ID: todo.ID, Title: todo.Title})
+		}
+	}
+
+	sort.Slice(result.Changes, func(i, j int) bool {
+		return result.Changes[i].ID < result.Changes[j].ID
+	})
+
+	return result
+}
+
+// CompareRepositories diffs every branch of two repositories, skipping unchanged branches
+func CompareRepositories(fromPrefix string, from *models.Repository, toPrefix string, to *models.Repository) []Result {
+	var results []Result
+	seen := make(map[string]bool)
+
+	for _, toBranch := range to.Branches {
+		seen[toBranch.Name] = true
+
+		var fromTodos []models.Todo
+		for _, fromBranch := range from.Branches {
+			if fromBranch.Name == toBranch.Name {
+				fromTodos = fromBranch.Todos
+				break
+			}
+		}
+
+		result := Compare(fromPrefix+toBranch.Name, fromTodos, toPrefix+toBranch.Name, toBranch.Todos)
+		if !result.Empty() {
+			results = append(results, result)
+		}
+	}
+
+	for _, fromBranch := range from.Branches {
+		if seen[fromBranch.Name] {
+			continue
+		}
+		result := Compare(fromPrefix+fromBranch.Name, fromBranch.Todos, toPrefix+fromBranch.Name, nil)
+		if !result.Empty() {
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// WriteText writes results in a human readable format, optionally colorized
+func WriteText(w io.Writer, results []Result, color bool) {
+	paint := func(c, s string) string {
+		if !color {
+			return s
+		}
+		return c + s + colorReset
+	}
+
+	for _, result := range results {
+		fmt.Fprintf(w, "diff %s..%s\n", result.From, result.To)
+		if result.Empty() {
+			fmt.Fprintln(w, "  No changes")
+			continue
+		}
+
+		for _, change := range result.Changes {
+			switch change.Type {
+			case Added:
+				fmt.Fprintln(w, paint(colorGreen, fmt.Sprintf("  + #%d %s", change.ID, change.Title)))
+			case Removed:
+				fmt.Fprintln(w, paint(colorRed, fmt.Sprintf("  - #%d %s", change.ID, change.Title)))
+			case Modified:
+				fmt.Fprintln(w, paint(colorYellow, fmt.Sprintf("  ~ #%d %s", change.ID, change.Title)))
+				for _, field := range change.Fields {
+					fmt.Fprintf(w, "      %s: %s → %s\n", field.Field, field.Before, field.After)
+				}
+			}
+		}
+	}
+}
+
+// WriteJSON writes results as indented JSON
+func WriteJSON(w io.Writer, results []Result) error {
+	if results == nil {
+		results = []Result{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
@@ -20,6 +20,10 @@ Examples:
   todo todo update 1 completed
   todo commit create "Implement user authentication"
   todo merge feature-auth`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Retry pushes queued while the remote was unreachable
+		commands.FlushPendingPushes(cmd)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println(`Todo CLI - Git-like task management
 
@@ -27,10 +31,20 @@ Usage:
   todo [command]
 
 Available Commands:
-  branch      Branch related commands (create, list, switch)
+  branch      Branch related commands (create, list, switch, delete, rename, copy)
   todo        Todo related commands (add, list, update)
   commit      Commit related commands (create, list, show)
+  stage       Stage completed todos for the next commit
+  unstage     Remove todos from the staging area
+  diff        Show todo changes between branches, commits and remotes
+  revert      Restore the todos of a commit in a new commit
+  cherry-pick Apply the todo changes of a commit onto the current branch
+  reset       Roll the current branch back to a commit
   merge       Merge a branch into current branch
+  import      Import todos from markdown, CSV, JSON issues or todo.txt
+  export      Export todos as markdown, CSV, iCalendar or todo.txt
+  bundle      Back up and restore all todo data (create, verify, restore)
+  git         Link todos to git commits and branches (link, install-hook)
   help        Help about any command
 
 Use "todo [command] --help" for more information about a command.`)
@@ -44,12 +58,26 @@ func main() {
 	rootCmd.AddCommand(commands.CommitCmd)
 	rootCmd.AddCommand(commands.MergeCmd)
 	rootCmd.AddCommand(commands.RemoteCmd)
+	rootCmd.AddCommand(commands.StageCmd)
+	rootCmd.AddCommand(commands.UnstageCmd)
+	rootCmd.AddCommand(commands.DiffCmd)
+	rootCmd.AddCommand(commands.RevertCmd)
+	rootCmd.AddCommand(commands.CherryPickCmd)
+	rootCmd.AddCommand(commands.ResetCmd)
+	rootCmd.AddCommand(commands.GitCmd)
+	rootCmd.AddCommand(commands.ImportCmd)
+	rootCmd.AddCommand(commands.ExportCmd)
+	rootCmd.AddCommand(commands.BundleCmd)
 	
 	// Add standalone remote commands
 	rootCmd.AddCommand(commands.PushCmd)
 	rootCmd.AddCommand(commands.PullCmd)
 	rootCmd.AddCommand(commands.FetchCmd)
 	rootCmd.AddCommand(commands.SyncCmd)
+	rootCmd.AddCommand(commands.WatchCmd)
+	rootCmd.AddCommand(commands.LoginCmd)
+	rootCmd.AddCommand(commands.LogoutCmd)
+	rootCmd.AddCommand(commands.ServeStdioCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println("Error:", err)
@@ -14,14 +14,21 @@ type Todo struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	BranchName  string    `json:"branch_name"`
+	GitCommits  []string  `json:"git_commits,omitempty"` // Git commits that reference this todo
+	ExternalID  string    `json:"external_id,omitempty"` // Stable ID of the item this todo was imported from
 }
 
 // Branch represents a development branch
 type Branch struct {
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	IsActive  bool      `json:"is_active"`
-	Todos     []Todo    `json:"todos"`
+	Name       string            `json:"name"`
+	CreatedAt  time.Time         `json:"created_at"`
+	IsActive   bool              `json:"is_active"`
+	Todos      []Todo            `json:"todos"`
+	Staged     []int             `json:"staged,omitempty"`      // Todo IDs staged for the next commit
+	Parent     string            `json:"parent,omitempty"`      // Branch this branch was created from
+	ForkPoint  string            `json:"fork_point,omitempty"`  // Last parent commit at creation time
+	Base       []Todo            `json:"base,omitempty"`        // Todo states at creation time, used as the merge base
+	MergeBases map[string][]Todo `json:"merge_bases,omitempty"` // Todo states at the last merge with each branch, by branch name
 }
 
 // Commit represents a commit with todos
@@ -29,16 +36,25 @@ type Commit struct {
 	ID        string    `json:"id"`
 	Message   string    `json:"message"`
 	Branch    string    `json:"branch"`
-	Todos     []int     `json:"todos"` // Todo IDs included in this commit
+	Todos     []int     `json:"todos"`              // Todo IDs included in this commit
+	Snapshot  []Todo    `json:"snapshot,omitempty"` // Todo states recorded at commit time
+	Before    []Todo    `json:"before,omitempty"`   // Previously committed states of those todos
 	CreatedAt time.Time `json:"created_at"`
 	Author    string    `json:"author"`
 }
 
 // Remote represents a remote repository
 type Remote struct {
-	Name string `json:"name"`
-	URL  string `json:"url"`
-	Type string `json:"type"` // "http", "file", "git"
+	Name               string `json:"name"`
+	URL                string `json:"url"`
+	Type               string `json:"type"`                           // "http", "file", "git", "s3", "ssh" or an external transport
+	CredentialHelper   string `json:"credential_helper,omitempty"`    // Executable asked for credentials
+	CACert             string `json:"ca_cert,omitempty"`              // PEM bundle of trusted CAs for HTTPS
+	ClientCert         string `json:"client_cert,omitempty"`          // PEM client certificate for mutual TLS
+	ClientKey          string `json:"client_key,omitempty"`           // PEM client key for mutual TLS
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"` // Disable certificate checks, testing only
+	MaxAttempts        int    `json:"max_attempts,omitempty"`         // Tries per operation for transient failures, 0 for the default
+	Timeout            string `json:"timeout,omitempty"`              // Per-request timeout such as "30s", empty for the default
 }
 
 // Repository represents the entire todo repository
@@ -50,3 +66,54 @@ type Repository struct {
 	Remotes       []Remote `json:"remotes"`
 	LastSync      time.Time `json:"last_sync"`
 }
+
+// Event announces a change to a repository on a sync server
+type Event struct {
+	Type       string          `json:"type"` // "push", "restore"
+	Repository string          `json:"repository"`
+	User       string          `json:"user,omitempty"`
+	Time       time.Time       `json:"time"`
+	Branches   []BranchSummary `json:"branches,omitempty"`
+	Commits    []CommitSummary `json:"commits,omitempty"` // Commits new to the server
+}
+
+// BranchSummary describes a branch in an event
+type BranchSummary struct {
+	Name      string `json:"name"`
+	Todos     int    `json:"todos"`
+	Completed int    `json:"completed"`
+}
+
+// CommitSummary describes a commit in an event
+type CommitSummary struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	Branch  string `json:"branch"`
+	Author  string `json:"author"`
+}
+
+// Todo statuses and priorities accepted by the CLI and the server
+var (
+	TodoStatuses   = []string{"pending", "in-progress", "completed"}
+	TodoPriorities = []string{"low", "medium", "high"}
+)
+
+// ValidStatus reports whether s is a known todo status
+func ValidStatus(s string) bool {
+	for _, status := range TodoStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidPriority reports whether p is a known todo priority
+func ValidPriority(p string) bool {
+	for _, priority := range TodoPriorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
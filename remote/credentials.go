@@ -0,0 +1,154 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"todo-cli/models"
+	"todo-cli/storage"
+)
+
+// Credential is a username/password pair or an API token for a remote
+type Credential struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// setAuth adds the best available credentials for a remote to a request.
+// The credential helper is asked first, then the credentials file, and
+// finally the TODO_CLI_USERNAME/TODO_CLI_PASSWORD environment variables.
+func (r *RemoteService) setAuth(req *http.Request, remote models.Remote) error {
+	if remote.CredentialHelper != "" {
+		cred, err := RunCredentialHelper(remote.CredentialHelper, "get", remote, nil)
+		if err != nil {
+			return err
+		}
+		if cred.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+cred.Token)
+			return nil
+		}
+		if cred.Username != "" && cred.Password != "" {
+			req.SetBasicAuth(cred.Username, cred.Password)
+			return nil
+		}
+	}
+
+	credentials, err := storage.NewStorage().LoadCredentials()
+	if err != nil {
+		return err
+	}
+	if stored, ok := credentials[storage.CredentialKey(remote.URL)]; ok && stored.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+stored.Token)
+		return nil
+	}
+
+	if username := os.Getenv("TODO_CLI_USERNAME"); username != "" {
+		if password := os.Getenv("TODO_CLI_PASSWORD"); password != "" {
+			req.SetBasicAuth(username, password)
+		}
+	}
+
+	return nil
+}
+
+// Login exchanges a username and password for an API token from an HTTP remote
+func (r *RemoteService) Login(remote models.Remote, username, password string) (string, error) {
+	if remote.Type != "http" {
+		return "", fmt.Errorf("login is only supported for http remotes")
+	}
+
+	req, err := http.NewRequest("POST", remote.URL+"/login", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(username, password)
+
+	client, err := r.clientFor(remote)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to contact remote: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", httpError(resp)
+	}
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil || result.Token == "" {
+		return "", fmt.Errorf("server did not return a token")
+	}
+
+	return result.Token, nil
+}
+
+// RunCredentialHelper runs a git-style credential helper. The helper receives
+// the action ("get", "store" or "erase") as its argument and key=value lines
+// on stdin describing the remote (and, for store, the credential). For get,
+// it prints username=, password= and/or token= lines on stdout.
+//
+// A helper name without a path separator refers to a todo-credential-<name>
+// executable on PATH.
+func RunCredentialHelper(helper, action string, remote models.Remote, cred *Credential) (*Credential, error) {
+	fields := strings.Fields(helper)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty credential helper")
+	}
+	if !strings.ContainsRune(fields[0], filepath.Separator) {
+		fields[0] = "todo-credential-" + fields[0]
+	}
+
+	var input bytes.Buffer
+	fmt.Fprintf(&input, "remote=%s\nurl=%s\n", remote.Name, remote.URL)
+	if cred != nil {
+		if cred.Username != "" {
+			fmt.Fprintf(&input, "username=%s\n", cred.Username)
+		}
+		if cred.Token != "" {
+			fmt.Fprintf(&input, "token=%s\n", cred.Token)
+		}
+	}
+	input.WriteString("\n")
+
+	var output bytes.Buffer
+	cmd := exec.Command(fields[0], append(fields[1:], action)...)
+	cmd.Stdin = &input
+	cmd.Stdout = &output
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("credential helper %s failed: %w", fields[0], err)
+	}
+
+	result := &Credential{}
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "username":
+			result.Username = value
+		case "password":
+			result.Password = value
+		case "token":
+			result.Token = value
+		}
+	}
+
+	return result, nil
+}
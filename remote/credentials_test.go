@@ -0,0 +1,100 @@
+package remote
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"todo-cli/models"
+	"todo-cli/storage"
+)
+
+func TestSetAuthUsesCredentialsOfURL(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TODO_CLI_USERNAME", "")
+	credentials := map[string]storage.Credential{storage.CredentialKey("https://a.example.com"): {Username: "alice", Token: "secret"}}
+	if err := storage.NewStorage().SaveCredentials(credentials); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRemoteService()
+	tests := map[string]string{
+		"https://a.example.com/": "Bearer secret",
+		"https://b.example.com":  "",
+	}
+	for url, want := range tests {
+		req, _ := http.NewRequest("GET", url, nil)
+		if err := r.setAuth(req, models.Remote{Name: "origin", URL: url, Type: "http"}); err != nil {
+			t.Fatal(err)
+		}
+		if got := req.Header.Get("Authorization"); got != want {
+			t.Errorf("Authorization for %s = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestCredentialHelperProtocol(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+	dir := t.TempDir()
+	received := filepath.Join(dir, "received")
+	helper := filepath.Join(dir, "helper")
+	script := "#!/bin/sh\n{ echo \"action=$1\"; cat; } >> '" + received + "'\n" +
+		"[ \"$1\" = get ] && printf 'username=alice\\npassword=hunter2\\nignored line\\n'\nexit 0\n"
+	if err := os.WriteFile(helper, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	remote := models.Remote{Name: "origin", URL: "https://todo.example.com"}
+
+	cred, err := RunCredentialHelper(helper, "get", remote, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Username != "alice" || cred.Password != "hunter2" || cred.Token != "" {
+		t.Errorf("credential = %+v", cred)
+	}
+	if _, err := RunCredentialHelper(helper, "store", remote, &Credential{Username: "alice", Token: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	input, _ := os.ReadFile(received)
+	want := "action=get\nremote=origin\nurl=https://todo.example.com\n\n" +
+		"action=store\nremote=origin\nurl=https://todo.example.com\nusername=alice\ntoken=secret\n\n"
+	if string(input) != want {
+		t.Errorf("helper input = %q, want %q", input, want)
+	}
+
+	if _, err := RunCredentialHelper(filepath.Join(dir, "missing"), "get", remote, nil); err == nil {
+		t.Error("missing helper succeeded")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		username, password, ok := req.BasicAuth()
+		if req.URL.Path != "/login" || !ok || username != "alice" || password != "hunter2" {
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+	}))
+	defer server.Close()
+
+	r := NewRemoteService()
+	remote := models.Remote{Name: "origin", URL: server.URL, Type: "http"}
+	token, err := r.Login(remote, "alice", "hunter2")
+	if err != nil || token != "secret" {
+		t.Errorf("Login = %q, %v, want the token", token, err)
+	}
+	if _, err := r.Login(remote, "alice", "wrong"); err == nil {
+		t.Error("login with a wrong password succeeded")
+	}
+	if _, err := r.Login(models.Remote{Name: "box", URL: "/srv/todos.json", Type: "file"}, "alice", "hunter2"); err == nil || !strings.Contains(err.Error(), "http") {
+		t.Errorf("login to a file remote = %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package remote
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"todo-cli/models"
+)
+
+// WatchEvents subscribes to the server-sent event stream of an HTTP remote and
+// calls handle for every event until the stream ends or handle returns an error
+func (r *RemoteService) WatchEvents(remote models.Remote, handle func(models.Event) error) error {
+	if remote.Type != "http" {
+		return fmt.Errorf("watching is only supported for http remotes")
+	}
+
+	req, err := http.NewRequest("GET", remote.URL+"/events", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	if err := r.setAuth(req, remote); err != nil {
+		return err
+	}
+
+	client, err := r.clientFor(remote)
+	if err != nil {
+		return err
+	}
+
+	// The stream stays open indefinitely, so drop the request timeout
+	streamClient := *client
+	streamClient.Timeout = 0
+
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to remote: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return httpError(resp)
+	}
+
+	var data strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// A blank line terminates an event
+		if line == "" {
+			if data.Len() > 0 {
+				var event models.Event
+				if err := json.Unmarshal([]byte(data.String()), &event); err == nil {
+					if err := handle(event); err != nil {
+						return err
+					}
+				}
+				data.Reset()
+			}
+			continue
+		}
+
+		if payload, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(payload, " "))
+		}
+	}
+
+	// A stream that was open and then broke is worth reconnecting to
+	if err := scanner.Err(); err != nil {
+		return &transientError{fmt.Errorf("event stream failed: %w", err)}
+	}
+	return &transientError{fmt.Errorf("event stream closed by remote")}
+}
@@ -0,0 +1,326 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"todo-cli/models"
+	"todo-cli/storage"
+)
+
+const (
+	// defaultGitBranch is the git branch holding todo data unless the remote
+	// URL names another one with a #branch suffix
+	defaultGitBranch = "todos"
+	gitDataDir       = "todos"
+	gitBranchesDir   = "branches"
+	gitCommitsFile   = "commits.json"
+	gitMetadataFile  = "repository.json"
+)
+
+// gitMetadata holds the repository-wide fields stored in a git remote
+type gitMetadata struct {
+	NextTodoID int `json:"next_todo_id"`
+}
+
+// gitRemote is a working clone of a git remote in the local data directory
+type gitRemote struct {
+	url     string
+	branch  string
+	dir     string
+	timeout time.Duration // Limit for each git command
+}
+
+// openGitRemote prepares the working clone of a git remote. The URL is
+// anything git can fetch from, optionally followed by #branch.
+func openGitRemote(remote models.Remote) (*gitRemote, error) {
+	repoURL, branch, _ := strings.Cut(remote.URL, "#")
+	if branch == "" {
+		branch = defaultGitBranch
+	}
+	// git would read a leading - as an option
+	if strings.HasPrefix(repoURL, "-") {
+		return nil, fmt.Errorf("invalid git URL '%s'", repoURL)
+	}
+	if err := checkGitBranch(branch); err != nil {
+		return nil, err
+	}
+	timeout, err := RemoteTimeout(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	// Key clones by URL so renaming or re-pointing a remote never mixes histories
+	sum := sha256.Sum256([]byte(repoURL))
+	g := &gitRemote{
+		url:     repoURL,
+		branch:  branch,
+		dir:     storage.NewStorage().GitCachePath(hex.EncodeToString(sum[:8])),
+		timeout: timeout,
+	}
+
+	if _, err := os.Stat(filepath.Join(g.dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(g.dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create git clone directory: %w", err)
+		}
+		if _, err := g.git("init", "--quiet"); err != nil {
+			return nil, err
+		}
+		if _, err := g.git("remote", "add", "--", "origin", repoURL); err != nil {
+			return nil, err
+		}
+	} else if _, err := g.git("remote", "set-url", "--", "origin", repoURL); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// checkGitBranch rejects branch names that git refuses or would read as an
+// option or as a reference to a previously checked out branch
+func checkGitBranch(branch string) error {
+	if strings.HasPrefix(branch, "-") || strings.Contains(branch, "@{") {
+		return fmt.Errorf("invalid git branch '%s'", branch)
+	}
+	if err := exec.Command("git", "check-ref-format", "--branch", branch).Run(); err != nil {
+		return fmt.Errorf("invalid git branch '%s'", branch)
+	}
+	return nil
+}
+
+// git runs the local git binary in the clone. Commands that run longer than
+// the remote's timeout are killed, so an unresponsive remote fails like an
+// unreachable one.
+func (g *gitRemote) git(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = g.dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Do not wait for ssh or helpers left holding git's output after it was killed
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", &transientError{fmt.Errorf("git %s: no response from %s within %s", args[0], g.url, g.timeout)}
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// fetch updates the clone to the remote branch. It reports false when the
+// branch does not exist on the remote yet.
+func (g *gitRemote) fetch() (bool, error) {
+	output, err := g.git("ls-remote", "--heads", "origin", g.branch)
+	if err != nil {
+		return false, err
+	}
+	if output == "" {
+		return false, nil
+	}
+
+	if _, err := g.git("fetch", "--quiet", "origin", g.branch); err != nil {
+		return false, err
+	}
+	if _, err := g.git("checkout", "--quiet", "--force", "-B", g.branch, "FETCH_HEAD"); err != nil {
+		return false, err
+	}
+	if _, err := g.git("clean", "--quiet", "-fdx"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// read loads the repository stored in the clone's work tree
+func (g *gitRemote) read() (*models.Repository, error) {
+	root := filepath.Join(g.dir, gitDataDir)
+	repo := &models.Repository{
+		Branches: []models.Branch{},
+		Commits:  []models.Commit{},
+	}
+
+	var metadata gitMetadata
+	if err := readJSON(filepath.Join(root, gitMetadataFile), &metadata); err != nil {
+		return nil, err
+	}
+	repo.NextTodoID = metadata.NextTodoID
+
+	if err := readJSON(filepath.Join(root, gitCommitsFile), &repo.Commits); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, gitBranchesDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read branches: %w", err)
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		var branch models.Branch
+		if err := readJSON(filepath.Join(root, gitBranchesDir, entry.Name()), &branch); err != nil {
+			return nil, err
+		}
+		repo.Branches = append(repo.Branches, branch)
+	}
+
+	return repo, nil
+}
+
+// write replaces the todo data in the clone's work tree with repo: one file
+// per branch, the commits and the repository-wide fields
+func (g *gitRemote) write(repo *models.Repository) error {
+	root := filepath.Join(g.dir, gitDataDir)
+	if err := os.RemoveAll(root); err != nil {
+		return fmt.Errorf("failed to clear todo data: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, gitBranchesDir), 0755); err != nil {
+		return fmt.Errorf("failed to create todo data directory: %w", err)
+	}
+
+	if err := writeJSON(filepath.Join(root, gitMetadataFile), gitMetadata{NextTodoID: repo.NextTodoID}); err != nil {
+		return err
+	}
+	if err := writeJSON(filepath.Join(root, gitCommitsFile), repo.Commits); err != nil {
+		return err
+	}
+	for _, branch := range repo.Branches {
+		// Local-only state stays out of the shared repository
+		branch.IsActive = false
+		branch.Staged = nil
+		name := url.PathEscape(branch.Name) + ".json"
+		if err := writeJSON(filepath.Join(root, gitBranchesDir, name), branch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pushGit commits the repository to the remote's git branch and pushes it.
+// Like the server, it refuses to drop remote commits unless force is set.
+func (r *RemoteService) pushGit(remote models.Remote, repo *models.Repository, force bool) error {
+	g, err := openGitRemote(remote)
+	if err != nil {
+		return err
+	}
+
+	exists, err := g.fetch()
+	if err != nil {
+		return err
+	}
+	if exists && !force {
+		current, err := g.read()
+		if err != nil {
+			return err
+		}
+		known := make(map[string]bool)
+		for _, commit := range repo.Commits {
+			known[commit.ID] = true
+		}
+		for _, commit := range current.Commits {
+			if !known[commit.ID] {
+				return fmt.Errorf("push rejected: the remote has commits you do not have, pull first or use --force")
+			}
+		}
+	}
+	if !exists {
+		// Start the branch without history, dropping any stale local copy
+		if _, err := g.git("update-ref", "-d", "refs/heads/"+g.branch); err != nil {
+			return err
+		}
+		if _, err := g.git("symbolic-ref", "HEAD", "refs/heads/"+g.branch); err != nil {
+			return err
+		}
+		if _, err := g.git("rm", "-r", "--quiet", "--cached", "--ignore-unmatch", "."); err != nil {
+			return err
+		}
+	}
+
+	if err := g.write(repo); err != nil {
+		return err
+	}
+	if _, err := g.git("add", "--all", gitDataDir); err != nil {
+		return err
+	}
+	if _, err := g.git("diff", "--cached", "--quiet"); err == nil && exists {
+		return nil // Nothing changed
+	}
+
+	message := fmt.Sprintf("Update todos: %d branches, %d commits", len(repo.Branches), len(repo.Commits))
+	args := []string{"commit", "--quiet", "-m", message}
+	if name, _ := g.git("config", "user.name"); name == "" {
+		args = append([]string{"-c", "user.name=todo-cli", "-c", "user.email=todo-cli@localhost"}, args...)
+	}
+	if _, err := g.git(args...); err != nil {
+		return err
+	}
+
+	pushArgs := []string{"push", "--quiet", "origin", g.branch}
+	if force {
+		pushArgs = []string{"push", "--quiet", "--force", "origin", g.branch}
+	}
+	if _, err := g.git(pushArgs...); err != nil {
+		return fmt.Errorf("push rejected, pull first: %w", err)
+	}
+
+	return nil
+}
+
+// pullGit fetches the remote's git branch and reads the repository from it
+func (r *RemoteService) pullGit(remote models.Remote) (*models.Repository, error) {
+	g, err := openGitRemote(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := g.fetch()
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("git branch '%s' does not exist on %s yet, push first", g.branch, g.url)
+	}
+
+	return g.read()
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package remote
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"todo-cli/models"
+)
+
+// bareGitRemote returns a git remote backed by a new bare repository, with
+// the clone cache in a temporary home directory
+func bareGitRemote(t *testing.T, branch string) models.Remote {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	bare := filepath.Join(t.TempDir(), "todos.git")
+	if output, err := exec.Command("git", "init", "--quiet", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, output)
+	}
+	url := bare
+	if branch != "" {
+		url += "#" + branch
+	}
+	return models.Remote{Name: "origin", URL: url, Type: "git"}
+}
+
+func TestGitPushAndPull(t *testing.T) {
+	remote := bareGitRemote(t, "todo-data")
+	r := NewRemoteService()
+
+	if _, err := r.pullGit(remote); err == nil || !strings.Contains(err.Error(), "push first") {
+		t.Errorf("pull before push = %v, want a missing branch error", err)
+	}
+
+	if err := r.pushGit(remote, testRepository(), false); err != nil {
+		t.Fatal(err)
+	}
+	pulled, err := r.pullGit(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pulled.NextTodoID != 2 || len(pulled.Commits) != 1 || len(pulled.Branches) != 1 {
+		t.Fatalf("pulled = %+v", pulled)
+	}
+	branch := pulled.Branches[0]
+	if branch.Name != "main" || len(branch.Todos) != 1 || branch.Todos[0].Title != "Ship" {
+		t.Errorf("pulled branch = %+v", branch)
+	}
+	if branch.IsActive || len(branch.Staged) != 0 {
+		t.Errorf("local-only state was pushed: %+v", branch)
+	}
+
+	// Pushing the same data again creates no git commit
+	if err := r.pushGit(remote, testRepository(), false); err != nil {
+		t.Fatal(err)
+	}
+	bare, _, _ := strings.Cut(remote.URL, "#")
+	output, err := exec.Command("git", "--git-dir", bare, "rev-list", "--count", "todo-data").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.TrimSpace(string(output)); count != "1" {
+		t.Errorf("git commits on todo-data = %s, want 1", count)
+	}
+}
+
+func TestGitPushRejectsMissingCommits(t *testing.T) {
+	remote := bareGitRemote(t, "")
+	r := NewRemoteService()
+
+	if err := r.pushGit(remote, testRepository(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	other := testRepository()
+	other.Commits = nil
+	if err := r.pushGit(remote, other, false); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("push without the remote commits = %v, want rejected", err)
+	}
+	if err := r.pushGit(remote, other, true); err != nil {
+		t.Errorf("forced push = %v", err)
+	}
+
+	pulled, err := r.pullGit(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pulled.Commits) != 0 {
+		t.Errorf("commits after forced push = %+v", pulled.Commits)
+	}
+}
+
+func TestGitRemoteRejectsOptionLikeNames(t *testing.T) {
+	remote := bareGitRemote(t, "")
+	r := NewRemoteService()
+
+	for _, url := range []string{
+		remote.URL + "#--upload-pack=touch pwned",
+		remote.URL + "#-b",
+		remote.URL + "#bad..name",
+		remote.URL + "#@{-1}",
+		"--upload-pack=touch pwned",
+	} {
+		bad := remote
+		bad.URL = url
+		if err := r.pushGit(bad, testRepository(), false); err == nil || !strings.Contains(err.Error(), "invalid git") {
+			t.Errorf("push to %q = %v, want invalid", url, err)
+		}
+	}
+}
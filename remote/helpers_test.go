@@ -0,0 +1,34 @@
+package remote
+
+import (
+	"os"
+	"testing"
+	"todo-cli/models"
+)
+
+// TestMain lets the test binary stand in for todo-cli on the far side of
+// the ssh shim: with TODO_TEST_SERVE_STDIO set it runs "serve-stdio <path>"
+func TestMain(m *testing.M) {
+	if os.Getenv("TODO_TEST_SERVE_STDIO") != "" && len(os.Args) == 3 && os.Args[1] == "serve-stdio" {
+		if err := NewRemoteService().ServeStdio(os.Args[2], os.Stdin, os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// testRepository returns a repository with one todo and one commit
+func testRepository() *models.Repository {
+	return &models.Repository{
+		Branches: []models.Branch{{
+			Name:     "main",
+			IsActive: true,
+			Todos:    []models.Todo{{ID: 1, Title: "Ship", Status: "completed", Priority: "high", BranchName: "main"}},
+			Staged:   []int{1},
+		}},
+		Commits:       []models.Commit{{ID: "c1", Message: "First", Branch: "main", Todos: []int{1}}},
+		CurrentBranch: "main",
+		NextTodoID:    2,
+	}
+}
@@ -2,62 +2,148 @@ package remote
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+	"todo-cli/bundle"
+	"todo-cli/diff"
 	"todo-cli/models"
 )
 
 // RemoteService handles remote repository operations
 type RemoteService struct {
-	client *http.Client
+	client     *http.Client
+	transports map[string]Transport
+
+	// OnRetry, when set, is called before waiting to retry a failed operation
+	OnRetry func(remote models.Remote, attempt int, wait time.Duration, err error)
 }
 
 // NewRemoteService creates a new remote service
 func NewRemoteService() *RemoteService {
-	return &RemoteService{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+	client := &http.Client{
+		Timeout: defaultTimeout,
+	}
+	r := &RemoteService{
+		client:     client,
+		transports: make(map[string]Transport),
 	}
+	r.RegisterTransport("http", &httpTransport{service: r})
+	r.RegisterTransport("file", &fileTransport{service: r})
+	r.RegisterTransport("git", &gitTransport{service: r})
+	r.RegisterTransport("s3", &s3Transport{service: r})
+	r.RegisterTransport("ssh", &sshTransport{})
+	return r
 }
 
-// PushRepository pushes the repository to a remote server
-func (r *RemoteService) PushRepository(remote models.Remote, repo *models.Repository) error {
-	switch remote.Type {
-	case "http":
-		return r.pushHTTP(remote, repo)
-	case "file":
-		return r.pushFile(remote, repo)
-	default:
-		return fmt.Errorf("unsupported remote type: %s", remote.Type)
+// clientFor returns an HTTP client configured with the TLS settings of a remote
+func (r *RemoteService) clientFor(remote models.Remote) (*http.Client, error) {
+	timeout, err := RemoteTimeout(remote)
+	if err != nil {
+		return nil, err
+	}
+	if remote.CACert == "" && remote.ClientCert == "" && !remote.InsecureSkipVerify {
+		if timeout == r.client.Timeout {
+			return r.client, nil
+		}
+		return &http.Client{Timeout: timeout, Transport: r.client.Transport}, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: remote.InsecureSkipVerify}
+
+	if remote.CACert != "" {
+		pem, err := os.ReadFile(remote.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", remote.CACert)
+		}
+		tlsConfig.RootCAs = pool
 	}
+
+	if remote.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(remote.ClientCert, remote.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}, nil
+}
+
+// PushRepository pushes the repository to a remote server. Force overwrites
+// remote changes that are missing locally, where the remote supports it.
+// Transient failures are retried with backoff.
+func (r *RemoteService) PushRepository(remote models.Remote, repo *models.Repository, force bool) error {
+	transport, err := r.Transport(remote.Type)
+	if err != nil {
+		return err
+	}
+	return r.retry(remote, func() error {
+		return transport.Push(remote, repo, force)
+	})
 }
 
 // PullRepository pulls the repository from a remote server
 func (r *RemoteService) PullRepository(remote models.Remote) (*models.Repository, error) {
-	switch remote.Type {
-	case "http":
-		return r.pullHTTP(remote)
-	case "file":
-		return r.pullFile(remote)
-	default:
-		return nil, fmt.Errorf("unsupported remote type: %s", remote.Type)
+	transport, err := r.Transport(remote.Type)
+	if err != nil {
+		return nil, err
+	}
+	var repo *models.Repository
+	err = r.retry(remote, func() error {
+		repo, err = transport.Pull(remote)
+		return err
+	})
+	return repo, err
+}
+
+// FetchRepository reads the repository from a remote for inspection
+func (r *RemoteService) FetchRepository(remote models.Remote) (*models.Repository, error) {
+	transport, err := r.Transport(remote.Type)
+	if err != nil {
+		return nil, err
 	}
+	var repo *models.Repository
+	err = r.retry(remote, func() error {
+		repo, err = transport.Fetch(remote)
+		return err
+	})
+	return repo, err
 }
 
 // pushHTTP pushes repository to HTTP server
-func (r *RemoteService) pushHTTP(remote models.Remote, repo *models.Repository) error {
+func (r *RemoteService) pushHTTP(remote models.Remote, repo *models.Repository, force bool) error {
 	data, err := json.Marshal(repo)
 	if err != nil {
 		return fmt.Errorf("failed to marshal repository: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", remote.URL+"/push", bytes.NewBuffer(data))
+	url := remote.URL + "/push"
+	if force {
+		url += "?force=true"
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -65,21 +151,23 @@ func (r *RemoteService) pushHTTP(remote models.Remote, repo *models.Repository)
 	req.Header.Set("Content-Type", "application/json")
 	
 	// Add authentication if credentials are available
-	if username := os.Getenv("TODO_CLI_USERNAME"); username != "" {
-		if password := os.Getenv("TODO_CLI_PASSWORD"); password != "" {
-			req.SetBasicAuth(username, password)
-		}
+	if err := r.setAuth(req, remote); err != nil {
+		return err
 	}
 
-	resp, err := r.client.Do(req)
+	client, err := r.clientFor(remote)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to push to remote: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server error: %s", string(body))
+		return httpError(resp)
 	}
 
 	return nil
@@ -93,21 +181,23 @@ func (r *RemoteService) pullHTTP(remote models.Remote) (*models.Repository, erro
 	}
 
 	// Add authentication if credentials are available
-	if username := os.Getenv("TODO_CLI_USERNAME"); username != "" {
-		if password := os.Getenv("TODO_CLI_PASSWORD"); password != "" {
-			req.SetBasicAuth(username, password)
-		}
+	if err := r.setAuth(req, remote); err != nil {
+		return nil, err
 	}
 
-	resp, err := r.client.Do(req)
+	client, err := r.clientFor(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to pull from remote: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("server error: %s", string(body))
+		return nil, httpError(resp)
 	}
 
 	var repo models.Repository
@@ -119,10 +209,40 @@ func (r *RemoteService) pullHTTP(remote models.Remote) (*models.Repository, erro
 	return &repo, nil
 }
 
-// pushFile pushes repository to file system
+// httpError converts a failed server response into an error
+func httpError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	message := strings.TrimSpace(string(body))
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("authentication failed: %s (run 'todo login' or set TODO_CLI_USERNAME and TODO_CLI_PASSWORD)", message)
+	case http.StatusForbidden:
+		return fmt.Errorf("permission denied: %s", message)
+	case http.StatusConflict:
+		return fmt.Errorf("push rejected: %s", message)
+	default:
+		if resp.StatusCode >= 500 {
+			return &transientError{fmt.Errorf("server error: %s", message)}
+		}
+		return fmt.Errorf("server error: %s", message)
+	}
+}
+
+// pushFile pushes repository to file system. Bundle paths get a bundle
+// holding only the repository, so it can be carried to offline machines.
 func (r *RemoteService) pushFile(remote models.Remote, repo *models.Repository) error {
+	path := filePath(remote)
+	if bundle.IsBundle(path) {
+		data, err := json.MarshalIndent(repo, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal repository: %w", err)
+		}
+		return bundle.Write(path, []bundle.File{{Path: bundle.RepositoryFile, Mode: 0644, Data: data}})
+	}
+
 	// Ensure directory exists
-	dir := filepath.Dir(remote.URL)
+	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
@@ -132,7 +252,7 @@ func (r *RemoteService) pushFile(remote models.Remote, repo *models.Repository)
 		return fmt.Errorf("failed to marshal repository: %w", err)
 	}
 
-	err = os.WriteFile(remote.URL, data, 0644)
+	err = os.WriteFile(path, data, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
@@ -140,9 +260,21 @@ func (r *RemoteService) pushFile(remote models.Remote, repo *models.Repository)
 	return nil
 }
 
-// pullFile pulls repository from file system
+// pullFile pulls repository from file system or from a verified bundle
 func (r *RemoteService) pullFile(remote models.Remote) (*models.Repository, error) {
-	data, err := os.ReadFile(remote.URL)
+	path := filePath(remote)
+	if bundle.IsBundle(path) {
+		b, err := bundle.Read(path)
+		if err != nil {
+			return nil, err
+		}
+		if problems := b.Verify(); len(problems) > 0 {
+			return nil, fmt.Errorf("bundle %s failed verification: %v", path, problems[0])
+		}
+		return b.Repository()
+	}
+
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
@@ -156,9 +288,16 @@ func (r *RemoteService) pullFile(remote models.Remote) (*models.Repository, erro
 	return &repo, nil
 }
 
-// MergeRepositories merges a remote repository with local repository
-func (r *RemoteService) MergeRepositories(local, remote *models.Repository) *models.Repository {
+// filePath returns the path of a file remote, which may be given as a file:// URL
+func filePath(remote models.Remote) string {
+	return strings.TrimPrefix(remote.URL, "file://")
+}
+
+// MergeRepositories merges a remote repository with local repository and
+// reports what the merge changed locally
+func (r *RemoteService) MergeRepositories(local, remote *models.Repository) (*models.Repository, *MergeReport) {
 	merged := *local // Start with local copy
+	report := newMergeReport()
 
 	// Merge branches
 	for _, remoteBranch := range remote.Branches {
@@ -166,7 +305,7 @@ func (r *RemoteService) MergeRepositories(local, remote *models.Repository) *mod
 		for i, localBranch := range merged.Branches {
 			if localBranch.Name == remoteBranch.Name {
 				// Merge todos from remote branch
-				merged.Branches[i] = r.mergeBranches(localBranch, remoteBranch)
+				merged.Branches[i] = r.mergeBranches(localBranch, remoteBranch, report)
 				found = true
 				break
 			}
@@ -174,6 +313,10 @@ func (r *RemoteService) MergeRepositories(local, remote *models.Repository) *mod
 		if !found {
 			// Add new branch from remote
 			merged.Branches = append(merged.Branches, remoteBranch)
+			report.NewBranches = append(report.NewBranches, remoteBranch.Name)
+			for _, todo := range remoteBranch.Todos {
+				report.NewTodos = append(report.NewTodos, ReportTodo{Branch: remoteBranch.Name, ID: todo.ID, Title: todo.Title})
+			}
 		}
 	}
 
@@ -186,6 +329,12 @@ func (r *RemoteService) MergeRepositories(local, remote *models.Repository) *mod
 	for _, remoteCommit := range remote.Commits {
 		if !commitMap[remoteCommit.ID] {
 			merged.Commits = append(merged.Commits, remoteCommit)
+			report.NewCommits = append(report.NewCommits, ReportEntry{
+				ID:      remoteCommit.ID,
+				Branch:  remoteCommit.Branch,
+				Message: remoteCommit.Message,
+				Author:  remoteCommit.Author,
+			})
 		}
 	}
 
@@ -196,11 +345,11 @@ func (r *RemoteService) MergeRepositories(local, remote *models.Repository) *mod
 
 	merged.LastSync = time.Now()
 
-	return &merged
+	return &merged, report
 }
 
-// mergeBranches merges todos from two branches
-func (r *RemoteService) mergeBranches(local, remote models.Branch) models.Branch {
+// mergeBranches merges todos from two branches, recording the changes in report
+func (r *RemoteService) mergeBranches(local, remote models.Branch, report *MergeReport) models.Branch {
 	merged := local
 	todoMap := make(map[int]bool)
 
@@ -213,13 +362,27 @@ func (r *RemoteService) mergeBranches(local, remote models.Branch) models.Branch
 	for _, remoteTodo := range remote.Todos {
 		if !todoMap[remoteTodo.ID] {
 			merged.Todos = append(merged.Todos, remoteTodo)
+			report.NewTodos = append(report.NewTodos, ReportTodo{Branch: local.Name, ID: remoteTodo.ID, Title: remoteTodo.Title})
 		} else {
 			// Update existing todo if remote is newer
 			for i, localTodo := range merged.Todos {
-				if localTodo.ID == remoteTodo.ID && remoteTodo.UpdatedAt.After(localTodo.UpdatedAt) {
+				if localTodo.ID != remoteTodo.ID {
+					continue
+				}
+				fields := diff.FieldChanges(localTodo, remoteTodo)
+				entry := ReportTodo{Branch: local.Name, ID: remoteTodo.ID, Title: remoteTodo.Title, Fields: fields}
+				if remoteTodo.UpdatedAt.After(localTodo.UpdatedAt) {
 					merged.Todos[i] = remoteTodo
-					break
+					if len(fields) > 0 {
+						report.UpdatedTodos = append(report.UpdatedTodos, entry)
+					}
+				} else if len(fields) > 0 {
+					// Show what the remote had against the version that was kept
+					entry.Title = localTodo.Title
+					entry.Fields = diff.FieldChanges(remoteTodo, localTodo)
+					report.LocalWins = append(report.LocalWins, entry)
 				}
+				break
 			}
 		}
 	}
@@ -0,0 +1,108 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"todo-cli/diff"
+)
+
+// MergeReport describes what merging a remote repository changed locally
+type MergeReport struct {
+	NewBranches  []string      `json:"new_branches"`
+	NewTodos     []ReportTodo  `json:"new_todos"`
+	UpdatedTodos []ReportTodo  `json:"updated_todos"` // Remote version was newer and replaced the local one
+	LocalWins    []ReportTodo  `json:"local_wins"`    // Remote version differed but the local one was kept
+	NewCommits   []ReportEntry `json:"new_commits"`
+}
+
+// ReportTodo is a todo in a merge report. For updated todos the fields go
+// from the local version to the remote one; for local wins from the remote
+// version to the local one that was kept.
+type ReportTodo struct {
+	Branch string             `json:"branch"`
+	ID     int                `json:"id"`
+	Title  string             `json:"title"`
+	Fields []diff.FieldChange `json:"fields,omitempty"`
+}
+
+// ReportEntry is a commit in a merge report
+type ReportEntry struct {
+	ID      string `json:"id"`
+	Branch  string `json:"branch"`
+	Message string `json:"message"`
+	Author  string `json:"author,omitempty"`
+}
+
+func newMergeReport() *MergeReport {
+	return &MergeReport{
+		NewBranches:  []string{},
+		NewTodos:     []ReportTodo{},
+		UpdatedTodos: []ReportTodo{},
+		LocalWins:    []ReportTodo{},
+		NewCommits:   []ReportEntry{},
+	}
+}
+
+// Empty reports whether the merge brought in nothing
+func (m *MergeReport) Empty() bool {
+	return len(m.NewBranches) == 0 && len(m.NewTodos) == 0 && len(m.UpdatedTodos) == 0 && len(m.NewCommits) == 0
+}
+
+// WriteText writes a one-line-per-category summary, or every branch, todo,
+// field and commit when verbose is set
+func (m *MergeReport) WriteText(w io.Writer, verbose bool) {
+	if m.Empty() && len(m.LocalWins) == 0 {
+		fmt.Fprintln(w, "- Already up to date")
+		return
+	}
+
+	fmt.Fprintf(w, "- %d new branches\n", len(m.NewBranches))
+	if verbose {
+		for _, name := range m.NewBranches {
+			fmt.Fprintf(w, "    + %s\n", name)
+		}
+	}
+
+	fmt.Fprintf(w, "- %d new todos\n", len(m.NewTodos))
+	if verbose {
+		for _, todo := range m.NewTodos {
+			fmt.Fprintf(w, "    + #%d [%s] %s\n", todo.ID, todo.Branch, todo.Title)
+		}
+	}
+
+	fmt.Fprintf(w, "- %d updated todos\n", len(m.UpdatedTodos))
+	if verbose {
+		writeReportTodos(w, "~", m.UpdatedTodos)
+	}
+
+	if len(m.LocalWins) > 0 {
+		fmt.Fprintf(w, "- %d todos kept the newer local version\n", len(m.LocalWins))
+		if verbose {
+			writeReportTodos(w, "=", m.LocalWins)
+		}
+	}
+
+	fmt.Fprintf(w, "- %d new commits\n", len(m.NewCommits))
+	if verbose {
+		for _, commit := range m.NewCommits {
+			fmt.Fprintf(w, "    %s [%s] %s\n", commit.ID, commit.Branch, commit.Message)
+		}
+	}
+}
+
+func writeReportTodos(w io.Writer, marker string, todos []ReportTodo) {
+	for _, todo := range todos {
+		fmt.Fprintf(w, "    %s #%d [%s] %s\n", marker, todo.ID, todo.Branch, todo.Title)
+		for _, field := range todo.Fields {
+			fmt.Fprintf(w, "        %s: %s → %s\n", field.Field, field.Before, field.After)
+		}
+	}
+}
+
+// WriteJSON writes the report as indented JSON
+func (m *MergeReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
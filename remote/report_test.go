@@ -0,0 +1,102 @@
+package remote
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"todo-cli/diff"
+	"todo-cli/models"
+)
+
+func TestMergeRepositoriesReport(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	local := &models.Repository{
+		Branches: []models.Branch{{
+			Name: "main",
+			Todos: []models.Todo{
+				{ID: 1, Title: "Write docs", Status: "pending", UpdatedAt: older},
+				{ID: 2, Title: "Fix build", Status: "completed", UpdatedAt: newer},
+				{ID: 3, Title: "Same", Status: "pending", UpdatedAt: older},
+			},
+		}},
+		Commits:    []models.Commit{{ID: "c1", Message: "First", Branch: "main"}},
+		NextTodoID: 4,
+	}
+	remote := &models.Repository{
+		Branches: []models.Branch{
+			{
+				Name: "main",
+				Todos: []models.Todo{
+					{ID: 1, Title: "Write docs", Status: "completed", UpdatedAt: newer},
+					{ID: 2, Title: "Fix build", Status: "pending", UpdatedAt: older},
+					{ID: 3, Title: "Same", Status: "pending", UpdatedAt: newer},
+					{ID: 4, Title: "Release", Status: "pending", UpdatedAt: newer},
+				},
+			},
+			{
+				Name:  "feature",
+				Todos: []models.Todo{{ID: 5, Title: "Try idea", Status: "pending"}},
+			},
+		},
+		Commits: []models.Commit{
+			{ID: "c1", Message: "First", Branch: "main"},
+			{ID: "c2", Message: "Second", Branch: "feature", Author: "sam"},
+		},
+		NextTodoID: 6,
+	}
+
+	merged, report := NewRemoteService().MergeRepositories(local, remote)
+
+	if want := []string{"feature"}; !reflect.DeepEqual(report.NewBranches, want) {
+		t.Errorf("new branches = %v, want %v", report.NewBranches, want)
+	}
+	wantNew := []ReportTodo{
+		{Branch: "main", ID: 4, Title: "Release"},
+		{Branch: "feature", ID: 5, Title: "Try idea"},
+	}
+	if !reflect.DeepEqual(report.NewTodos, wantNew) {
+		t.Errorf("new todos = %+v, want %+v", report.NewTodos, wantNew)
+	}
+
+	// Todo #3 has a newer remote copy but no changed fields, so it is not reported
+	wantUpdated := []ReportTodo{{
+		Branch: "main", ID: 1, Title: "Write docs",
+		Fields: []diff.FieldChange{{Field: "status", Before: "pending", After: "completed"}},
+	}}
+	if !reflect.DeepEqual(report.UpdatedTodos, wantUpdated) {
+		t.Errorf("updated todos = %+v, want %+v", report.UpdatedTodos, wantUpdated)
+	}
+	wantLocal := []ReportTodo{{
+		Branch: "main", ID: 2, Title: "Fix build",
+		Fields: []diff.FieldChange{{Field: "status", Before: "pending", After: "completed"}},
+	}}
+	if !reflect.DeepEqual(report.LocalWins, wantLocal) {
+		t.Errorf("local wins = %+v, want %+v", report.LocalWins, wantLocal)
+	}
+
+	wantCommits := []ReportEntry{{ID: "c2", Branch: "feature", Message: "Second", Author: "sam"}}
+	if !reflect.DeepEqual(report.NewCommits, wantCommits) {
+		t.Errorf("new commits = %+v, want %+v", report.NewCommits, wantCommits)
+	}
+
+	if len(merged.Commits) != 2 {
+		t.Errorf("merged commits = %+v, want c1 and c2", merged.Commits)
+	}
+	if merged.NextTodoID != 6 {
+		t.Errorf("next todo ID = %d, want 6", merged.NextTodoID)
+	}
+	if todo := merged.Branches[0].Todos[1]; todo.Status != "completed" {
+		t.Errorf("todo #2 = %+v, want the local version kept", todo)
+	}
+}
+
+func TestMergeRepositoriesReportEmpty(t *testing.T) {
+	repo := testRepository()
+	_, report := NewRemoteService().MergeRepositories(testRepository(), repo)
+
+	if len(report.NewBranches)+len(report.NewTodos)+len(report.UpdatedTodos)+len(report.LocalWins)+len(report.NewCommits) != 0 {
+		t.Errorf("report for identical repositories = %+v, want empty", report)
+	}
+}
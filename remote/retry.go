@@ -0,0 +1,104 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+	"todo-cli/models"
+)
+
+const (
+	defaultMaxAttempts = 4
+	defaultTimeout     = 30 * time.Second
+	retryBaseDelay     = 500 * time.Millisecond
+	retryMaxDelay      = 10 * time.Second
+)
+
+// transientError marks a failure worth retrying, such as a 5xx response
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// IsTransient reports whether an error is a network or server failure that
+// may succeed when tried again, as opposed to e.g. a rejected push
+func IsTransient(err error) bool {
+	var transient *transientError
+	if errors.As(err, &transient) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// RemoteTimeout returns the per-request timeout configured for a remote
+func RemoteTimeout(remote models.Remote) (time.Duration, error) {
+	if remote.Timeout == "" {
+		return defaultTimeout, nil
+	}
+	timeout, err := time.ParseDuration(remote.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q, expected a duration such as 30s", remote.Timeout)
+	}
+	return timeout, nil
+}
+
+// retry runs op until it succeeds, fails permanently or the remote's attempt
+// limit is reached. The delay between attempts doubles from retryBaseDelay up
+// to retryMaxDelay, with jitter so clients that failed together do not retry
+// together.
+func (r *RemoteService) retry(remote models.Remote, op func() error) error {
+	attempts := remote.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultMaxAttempts
+	}
+
+	delay := retryBaseDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = op()
+		if err == nil || !IsTransient(err) {
+			return err
+		}
+		if attempt >= attempts {
+			break
+		}
+
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		if r.OnRetry != nil {
+			r.OnRetry(remote, attempt, wait, err)
+		}
+		time.Sleep(wait)
+
+		delay *= 2
+		if delay > retryMaxDelay {
+			delay = retryMaxDelay
+		}
+	}
+
+	if attempts > 1 {
+		return fmt.Errorf("%w (gave up after %d attempts)", err, attempts)
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+	"todo-cli/models"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&transientError{errors.New("server error: overloaded")}, true},
+		{fmt.Errorf("push: %w", &transientError{errors.New("503")}), true},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, true},
+		{&net.DNSError{Err: "no such host", Name: "todo.invalid"}, true},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{io.ErrUnexpectedEOF, true},
+		{os.ErrDeadlineExceeded, true},
+		{errors.New("push rejected: the remote has commits you do not have"), false},
+		{fmt.Errorf("S3 access denied: %w", errors.New("403")), false},
+	}
+	for _, test := range tests {
+		if got := IsTransient(test.err); got != test.want {
+			t.Errorf("IsTransient(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	r := NewRemoteService()
+	var retries []int
+	r.OnRetry = func(_ models.Remote, attempt int, wait time.Duration, _ error) {
+		if wait > retryBaseDelay {
+			t.Errorf("first wait %s exceeds %s", wait, retryBaseDelay)
+		}
+		retries = append(retries, attempt)
+	}
+
+	// A transient failure is retried until the operation succeeds
+	calls := 0
+	err := r.retry(models.Remote{Name: "origin"}, func() error {
+		calls++
+		if calls == 1 {
+			return &transientError{errors.New("503")}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 || len(retries) != 1 {
+		t.Errorf("retry = %v after %d calls and retries %v, want success after 2 calls", err, calls, retries)
+	}
+
+	// Permanent failures are returned at once
+	calls, retries = 0, nil
+	rejected := errors.New("push rejected")
+	err = r.retry(models.Remote{Name: "origin"}, func() error {
+		calls++
+		return rejected
+	})
+	if err != rejected || calls != 1 || len(retries) != 0 {
+		t.Errorf("retry = %v after %d calls, want the rejection after 1 call", err, calls)
+	}
+
+	// The attempt limit of the remote is honored
+	calls, retries = 0, nil
+	err = r.retry(models.Remote{Name: "origin", MaxAttempts: 2}, func() error {
+		calls++
+		return &transientError{errors.New("503")}
+	})
+	if calls != 2 || !IsTransient(err) || err.Error() != "503 (gave up after 2 attempts)" {
+		t.Errorf("retry = %v after %d calls, want giving up after 2", err, calls)
+	}
+
+	// A single attempt is not retried or annotated
+	calls = 0
+	err = r.retry(models.Remote{Name: "origin", MaxAttempts: 1}, func() error {
+		calls++
+		return &transientError{errors.New("503")}
+	})
+	if calls != 1 || err.Error() != "503" {
+		t.Errorf("retry = %v after %d calls, want one attempt", err, calls)
+	}
+}
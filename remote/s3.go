@@ -0,0 +1,350 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"todo-cli/models"
+)
+
+// s3ObjectName is the object holding the repository under the remote's prefix
+const s3ObjectName = "repository.json"
+
+// s3Transport stores the repository as a single object in an S3-compatible
+// bucket. Remote URLs look like s3://bucket/optional/prefix.
+//
+// Credentials and endpoint come from the standard AWS environment variables:
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION (or
+// AWS_DEFAULT_REGION) and AWS_ENDPOINT_URL_S3 (or AWS_ENDPOINT_URL) for
+// services other than AWS.
+type s3Transport struct {
+	service *RemoteService
+}
+
+// s3Config is the resolved location and credentials of an S3 remote
+type s3Config struct {
+	endpoint     *url.URL
+	pathStyle    bool
+	bucket       string
+	key          string
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// s3ConfigFor parses an s3:// remote URL and reads the environment
+func s3ConfigFor(remote models.Remote) (*s3Config, error) {
+	u, err := url.Parse(remote.URL)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 remote URL %q, expected s3://bucket/prefix", remote.URL)
+	}
+
+	cfg := &s3Config{
+		bucket:       u.Host,
+		key:          s3ObjectName,
+		region:       firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if prefix := strings.Trim(u.Path, "/"); prefix != "" {
+		cfg.key = prefix + "/" + s3ObjectName
+	}
+	if cfg.region == "" {
+		cfg.region = "us-east-1"
+	}
+	if cfg.accessKey == "" || cfg.secretKey == "" {
+		return nil, fmt.Errorf("s3 credentials missing, set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	if endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); endpoint != "" {
+		// Custom endpoints (MinIO, Ceph, local fakes) rarely resolve bucket
+		// subdomains, so address buckets by path
+		cfg.endpoint, err = url.Parse(endpoint)
+		if err != nil || cfg.endpoint.Host == "" {
+			return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+		}
+		cfg.pathStyle = true
+	} else {
+		cfg.endpoint = &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", cfg.bucket, cfg.region)}
+	}
+
+	return cfg, nil
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// objectURL returns the URL of the repository object
+func (c *s3Config) objectURL() *url.URL {
+	u := *c.endpoint
+	path := "/" + c.key
+	if c.pathStyle {
+		path = strings.TrimSuffix(u.Path, "/") + "/" + c.bucket + path
+	}
+	u.Path = path
+	u.RawPath = s3Escape(path)
+	return &u
+}
+
+// get fetches the repository object. It returns nil data when the object does
+// not exist yet.
+func (t *s3Transport) get(client *http.Client, cfg *s3Config) ([]byte, string, error) {
+	req, err := http.NewRequest("GET", cfg.objectURL().String(), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	cfg.sign(req, nil, time.Now())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to reach S3: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read object: %w", err)
+		}
+		return data, resp.Header.Get("ETag"), nil
+	case http.StatusNotFound:
+		return nil, "", nil
+	default:
+		return nil, "", s3Error(resp)
+	}
+}
+
+// Push writes the repository object. Writes are conditional on the object
+// being unchanged since it was read, so concurrent pushes never clobber each
+// other.
+func (t *s3Transport) Push(remote models.Remote, repo *models.Repository, force bool) error {
+	cfg, err := s3ConfigFor(remote)
+	if err != nil {
+		return err
+	}
+
+	client, err := t.service.clientFor(remote)
+	if err != nil {
+		return err
+	}
+
+	current, etag, err := t.get(client, cfg)
+	if err != nil {
+		return err
+	}
+	if current != nil && !force {
+		var remoteRepo models.Repository
+		if err := json.Unmarshal(current, &remoteRepo); err != nil {
+			return fmt.Errorf("failed to decode remote repository: %w", err)
+		}
+		known := make(map[string]bool)
+		for _, commit := range repo.Commits {
+			known[commit.ID] = true
+		}
+		for _, commit := range remoteRepo.Commits {
+			if !known[commit.ID] {
+				return fmt.Errorf("push rejected: the remote has commits you do not have, pull first or use --force")
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(repo, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal repository: %w", err)
+	}
+
+	req, err := http.NewRequest("PUT", cfg.objectURL().String(), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if !force {
+		if etag == "" {
+			req.Header.Set("If-None-Match", "*")
+		} else {
+			req.Header.Set("If-Match", etag)
+		}
+	}
+	cfg.sign(req, data, time.Now())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push to S3: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return fmt.Errorf("push rejected: the remote changed while pushing, pull first or use --force")
+	default:
+		return s3Error(resp)
+	}
+}
+
+// Pull reads the repository object
+func (t *s3Transport) Pull(remote models.Remote) (*models.Repository, error) {
+	cfg, err := s3ConfigFor(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := t.service.clientFor(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _, err := t.get(client, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("no repository at %s yet, push first", remote.URL)
+	}
+
+	var repo models.Repository
+	if err := json.Unmarshal(data, &repo); err != nil {
+		return nil, fmt.Errorf("failed to decode repository: %w", err)
+	}
+
+	return &repo, nil
+}
+
+// Fetch reads the repository object
+func (t *s3Transport) Fetch(remote models.Remote) (*models.Repository, error) {
+	return t.Pull(remote)
+}
+
+func (t *s3Transport) Capabilities() Capabilities {
+	return Capabilities{Force: true, Rejects: true}
+}
+
+// s3Error converts a failed S3 response into an error, using the message of
+// the XML error document when there is one
+func s3Error(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	message := strings.TrimSpace(string(body))
+	if start := strings.Index(message, "<Message>"); start >= 0 {
+		if end := strings.Index(message, "</Message>"); end > start {
+			message = message[start+len("<Message>") : end]
+		}
+	}
+	if message == "" {
+		message = resp.Status
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("S3 access denied: %s (check the AWS_* environment variables)", message)
+	default:
+		if resp.StatusCode >= 500 {
+			return &transientError{fmt.Errorf("S3 error: %s", message)}
+		}
+		return fmt.Errorf("S3 error: %s", message)
+	}
+}
+
+// sign adds an AWS Signature Version 4 to the request
+func (c *s3Config) sign(req *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" || lower == "if-match" || lower == "if-none-match" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), date)
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape encodes an object path the way SigV4 expects: every byte except
+// unreserved characters and slashes is percent-encoded
+func s3Escape(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
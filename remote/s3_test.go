@@ -0,0 +1,237 @@
+package remote
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"todo-cli/models"
+)
+
+// fakeS3 is an S3-compatible object store that checks Signature Version 4
+// and conditional writes
+type fakeS3 struct {
+	secretKey string
+	region    string
+
+	mu        sync.Mutex
+	objects   map[string][]byte // by raw request path
+	etags     map[string]string
+	puts      []http.Header
+	beforePut func() // Runs before a PUT is applied, to simulate concurrent writers
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{
+		secretKey: "test-secret",
+		region:    "eu-west-1",
+		objects:   make(map[string][]byte),
+		etags:     make(map[string]string),
+	}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", f.secretKey)
+	t.Setenv("AWS_SESSION_TOKEN", "test-token")
+	t.Setenv("AWS_REGION", f.region)
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+	t.Setenv("AWS_ENDPOINT_URL", "")
+	return f, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := f.verify(r, body); err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>%s</Message></Error>", err)
+		return
+	}
+
+	path, _, _ := strings.Cut(r.RequestURI, "?")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case "GET":
+		data, ok := f.objects[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", f.etags[path])
+		w.Write(data)
+	case "PUT":
+		f.puts = append(f.puts, r.Header.Clone())
+		if f.beforePut != nil {
+			f.beforePut()
+		}
+		current, exists := f.etags[path]
+		if match := r.Header.Get("If-Match"); match != "" && match != current {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		f.store(path, body)
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// store replaces an object, giving it a new ETag
+func (f *fakeS3) store(path string, data []byte) {
+	f.objects[path] = data
+	f.etags[path] = `"` + sha256Hex(data)[:16] + `"`
+}
+
+// verify recomputes the request signature from what was received
+func (f *fakeS3) verify(r *http.Request, body []byte) error {
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return fmt.Errorf("not signed")
+	}
+	fields := make(map[string]string)
+	for _, field := range strings.Split(auth, ", ") {
+		name, value, _ := strings.Cut(field, "=")
+		fields[name] = value
+	}
+
+	if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+		return fmt.Errorf("payload hash mismatch")
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) != len("20060102T150405Z") {
+		return fmt.Errorf("bad date %q", amzDate)
+	}
+	scope := amzDate[:8] + "/" + f.region + "/s3/aws4_request"
+	if fields["Credential"] != "test-key/"+scope {
+		return fmt.Errorf("bad credential %q", fields["Credential"])
+	}
+
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signed) {
+		return fmt.Errorf("signed headers not sorted")
+	}
+	required := []string{"host", "x-amz-content-sha256", "x-amz-date", "x-amz-security-token"}
+	for _, name := range []string{"If-Match", "If-None-Match", "Content-Type"} {
+		if r.Header.Get(name) != "" {
+			required = append(required, strings.ToLower(name))
+		}
+	}
+	for _, name := range required {
+		if !strings.Contains(";"+fields["SignedHeaders"]+";", ";"+name+";") {
+			return fmt.Errorf("%s is not signed", name)
+		}
+	}
+
+	var headers strings.Builder
+	for _, name := range signed {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	path, query, _ := strings.Cut(r.RequestURI, "?")
+	canonical := strings.Join([]string{r.Method, path, query, headers.String(), fields["SignedHeaders"], sha256Hex(body)}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonical))}, "\n")
+
+	key := []byte("AWS4" + f.secretKey)
+	for _, part := range []string{amzDate[:8], f.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	want := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	if !hmac.Equal([]byte(fields["Signature"]), []byte(want)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func TestS3PushAndPull(t *testing.T) {
+	f, _ := newFakeS3(t)
+	transport := &s3Transport{service: NewRemoteService()}
+	// The prefix needs escaping in the signed path
+	remote := models.Remote{Name: "s3", URL: "s3://team-bucket/shared todos/ünïcode", Type: "s3"}
+
+	if _, err := transport.Pull(remote); err == nil || !strings.Contains(err.Error(), "push first") {
+		t.Errorf("pull before push = %v, want a missing object error", err)
+	}
+
+	if err := transport.Push(remote, testRepository(), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.objects["/team-bucket/shared%20todos/%C3%BCn%C3%AFcode/repository.json"]; !ok {
+		t.Errorf("objects = %v", f.objects)
+	}
+	if got := f.puts[0].Get("If-None-Match"); got != "*" {
+		t.Errorf("first push If-None-Match = %q, want *", got)
+	}
+
+	pulled, err := transport.Pull(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pulled.Commits) != 1 || len(pulled.Branches) != 1 || pulled.Branches[0].Todos[0].Title != "Ship" {
+		t.Errorf("pulled = %+v", pulled)
+	}
+
+	// Later pushes are conditional on the ETag read before them
+	if err := transport.Push(remote, testRepository(), false); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.puts[1].Get("If-Match"); got == "" {
+		t.Error("second push has no If-Match")
+	}
+}
+
+func TestS3PushRejections(t *testing.T) {
+	f, _ := newFakeS3(t)
+	transport := &s3Transport{service: NewRemoteService()}
+	remote := models.Remote{Name: "s3", URL: "s3://bucket", Type: "s3"}
+
+	if err := transport.Push(remote, testRepository(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	other := testRepository()
+	other.Commits = nil
+	if err := transport.Push(remote, other, false); err == nil || !strings.Contains(err.Error(), "commits you do not have") {
+		t.Errorf("push without the remote commits = %v, want rejected", err)
+	}
+
+	// Another writer replaces the object between our read and write
+	f.beforePut = func() { f.store("/bucket/repository.json", []byte(`{"commits":[]}`)) }
+	if err := transport.Push(remote, testRepository(), false); err == nil || !strings.Contains(err.Error(), "changed while pushing") {
+		t.Errorf("push racing another writer = %v, want rejected", err)
+	}
+
+	if err := transport.Push(remote, other, true); err != nil {
+		t.Errorf("forced push = %v", err)
+	}
+	last := f.puts[len(f.puts)-1]
+	if last.Get("If-Match") != "" || last.Get("If-None-Match") != "" {
+		t.Errorf("forced push is conditional: %v", last)
+	}
+}
+
+func TestS3WrongSecretIsDenied(t *testing.T) {
+	newFakeS3(t)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wrong")
+	transport := &s3Transport{service: NewRemoteService()}
+
+	_, err := transport.Pull(models.Remote{Name: "s3", URL: "s3://bucket", Type: "s3"})
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("pull with a wrong secret = %v, want access denied", err)
+	}
+}
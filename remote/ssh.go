@@ -0,0 +1,314 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"todo-cli/models"
+)
+
+const (
+	// defaultSSHHelper is the program run on the remote host, overridable
+	// with TODO_SSH_HELPER
+	defaultSSHHelper = "todo-cli"
+
+	lockTimeout = 10 * time.Second
+	lockStale   = 2 * time.Minute
+)
+
+// stdioRequest is the message a client sends to serve-stdio
+type stdioRequest struct {
+	Command    string             `json:"command"` // "pull" or "push"
+	Force      bool               `json:"force,omitempty"`
+	Repository *models.Repository `json:"repository,omitempty"`
+}
+
+// stdioResponse is the reply of serve-stdio. Status uses HTTP status codes so
+// errors mean the same as on a todo-cli server.
+type stdioResponse struct {
+	Status     int                `json:"status"`
+	Message    string             `json:"message,omitempty"`
+	Repository *models.Repository `json:"repository,omitempty"`
+}
+
+// sshTransport reaches a repository file on another machine by running
+// "todo-cli serve-stdio <path>" there over the system ssh binary. Remote
+// URLs look like ssh://user@host:port/path/repo.json; a path starting with
+// /~/ is relative to the remote home directory.
+//
+// TODO_SSH_COMMAND replaces the ssh command (e.g. "ssh -i ~/.ssh/todo_key")
+// and TODO_SSH_HELPER the program run on the remote side.
+type sshTransport struct{}
+
+func (t *sshTransport) Push(remote models.Remote, repo *models.Repository, force bool) error {
+	_, err := t.call(remote, stdioRequest{Command: "push", Force: force, Repository: repo})
+	return err
+}
+
+func (t *sshTransport) Pull(remote models.Remote) (*models.Repository, error) {
+	return t.call(remote, stdioRequest{Command: "pull"})
+}
+
+func (t *sshTransport) Fetch(remote models.Remote) (*models.Repository, error) {
+	return t.call(remote, stdioRequest{Command: "pull"})
+}
+
+func (t *sshTransport) Capabilities() Capabilities {
+	return Capabilities{Force: true, Rejects: true}
+}
+
+// sshCommand builds the ssh command line for a remote, killed when ctx ends
+func sshCommand(ctx context.Context, remote models.Remote) (*exec.Cmd, error) {
+	u, err := url.Parse(remote.URL)
+	if err != nil || u.Scheme != "ssh" || u.Hostname() == "" || u.Path == "" || u.Path == "/" {
+		return nil, fmt.Errorf("invalid ssh remote URL %q, expected ssh://[user@]host[:port]/path/repo.json", remote.URL)
+	}
+
+	sshArgs := strings.Fields(os.Getenv("TODO_SSH_COMMAND"))
+	if len(sshArgs) == 0 {
+		sshArgs = []string{"ssh"}
+	}
+	if port := u.Port(); port != "" {
+		sshArgs = append(sshArgs, "-p", port)
+	}
+	// ssh would read a leading - as an option such as -oProxyCommand
+	if strings.HasPrefix(u.Hostname(), "-") || strings.HasPrefix(u.User.Username(), "-") {
+		return nil, fmt.Errorf("invalid ssh remote URL %q: host and user must not start with '-'", remote.URL)
+	}
+	host := u.Hostname()
+	if u.User != nil {
+		host = u.User.Username() + "@" + host
+	}
+
+	helper := os.Getenv("TODO_SSH_HELPER")
+	if helper == "" {
+		helper = defaultSSHHelper
+	}
+
+	// ssh hands the command to the remote shell, so quote the path but let
+	// the shell expand a leading ~/
+	path := ShellQuote(u.Path)
+	if rest, ok := strings.CutPrefix(u.Path, "/~/"); ok {
+		path = "~/" + ShellQuote(rest)
+	}
+
+	sshArgs = append(sshArgs, "--", host, helper+" serve-stdio "+path)
+	cmd := exec.CommandContext(ctx, sshArgs[0], sshArgs[1:]...)
+	// Do not wait for processes ssh left holding its output after being killed
+	cmd.WaitDelay = time.Second
+	return cmd, nil
+}
+
+// ShellQuote quotes s as a single POSIX shell word
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// call sends one request to serve-stdio on the remote host, giving up after
+// the remote's timeout
+func (t *sshTransport) call(remote models.Remote, request stdioRequest) (*models.Repository, error) {
+	timeout, err := RemoteTimeout(remote)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd, err := sshCommand(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, &transientError{fmt.Errorf("ssh: no response from %s within %s", remote.URL, timeout)}
+	}
+
+	var response stdioResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		// ssh exits with 255 when it cannot reach the host
+		var exitErr *exec.ExitError
+		unreachable := errors.As(runErr, &exitErr) && exitErr.ExitCode() == 255
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			if unreachable {
+				return nil, &transientError{fmt.Errorf("ssh: %s", message)}
+			}
+			return nil, fmt.Errorf("ssh: %s", message)
+		}
+		if runErr != nil {
+			return nil, fmt.Errorf("ssh failed: %w", runErr)
+		}
+		return nil, fmt.Errorf("invalid response from %s serve-stdio: %w", remote.URL, err)
+	}
+
+	switch response.Status {
+	case http.StatusOK:
+		return response.Repository, nil
+	case http.StatusConflict:
+		return nil, fmt.Errorf("push rejected: %s", response.Message)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%s", response.Message)
+	default:
+		if response.Status >= 500 {
+			return nil, &transientError{fmt.Errorf("server error: %s", response.Message)}
+		}
+		return nil, fmt.Errorf("server error: %s", response.Message)
+	}
+}
+
+// ServeStdio answers one pull or push request for the repository file at
+// path, reading the request from in and writing the response to out. Pushes
+// follow the rules of the HTTP server: they are rejected when the stored
+// repository has commits the client lacks unless forced, and accepted ones
+// replace the stored repository, so deleted branches and todos stay deleted.
+// The check and the write happen while holding a lock file, so a concurrent
+// push cannot slip in between and be overwritten.
+func (r *RemoteService) ServeStdio(path string, in io.Reader, out io.Writer) error {
+	response := r.serveStdio(path, in)
+	return json.NewEncoder(out).Encode(response)
+}
+
+func (r *RemoteService) serveStdio(path string, in io.Reader) stdioResponse {
+	line, err := bufio.NewReader(in).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return stdioResponse{Status: http.StatusBadRequest, Message: "failed to read request"}
+	}
+	var request stdioRequest
+	if err := json.Unmarshal(line, &request); err != nil {
+		return stdioResponse{Status: http.StatusBadRequest, Message: "failed to parse request"}
+	}
+
+	switch request.Command {
+	case "pull":
+		repo, err := readRepositoryFile(path)
+		if os.IsNotExist(err) {
+			return stdioResponse{Status: http.StatusNotFound, Message: fmt.Sprintf("no repository at %s yet, push first", path)}
+		}
+		if err != nil {
+			return stdioResponse{Status: http.StatusInternalServerError, Message: err.Error()}
+		}
+		return stdioResponse{Status: http.StatusOK, Repository: repo}
+
+	case "push":
+		if request.Repository == nil {
+			return stdioResponse{Status: http.StatusBadRequest, Message: "push without a repository"}
+		}
+
+		unlock, err := lockFile(path + ".lock")
+		if err != nil {
+			return stdioResponse{Status: http.StatusServiceUnavailable, Message: err.Error()}
+		}
+		defer unlock()
+
+		stored, err := readRepositoryFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return stdioResponse{Status: http.StatusInternalServerError, Message: err.Error()}
+		}
+
+		if stored != nil && !request.Force {
+			known := make(map[string]bool)
+			for _, commit := range request.Repository.Commits {
+				known[commit.ID] = true
+			}
+			missing := 0
+			for _, commit := range stored.Commits {
+				if !known[commit.ID] {
+					missing++
+				}
+			}
+			if missing > 0 {
+				return stdioResponse{Status: http.StatusConflict, Message: fmt.Sprintf("Rejected: remote has %d commits you do not have, pull first", missing)}
+			}
+		}
+
+		if err := writeRepositoryFile(path, request.Repository); err != nil {
+			return stdioResponse{Status: http.StatusInternalServerError, Message: err.Error()}
+		}
+		return stdioResponse{Status: http.StatusOK, Message: "Push successful"}
+
+	default:
+		return stdioResponse{Status: http.StatusBadRequest, Message: fmt.Sprintf("unknown command %q", request.Command)}
+	}
+}
+
+func readRepositoryFile(path string) (*models.Repository, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var repo models.Repository
+	if err := json.Unmarshal(data, &repo); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &repo, nil
+}
+
+// writeRepositoryFile replaces the repository file atomically
+func writeRepositoryFile(path string, repo *models.Repository) error {
+	data, err := json.MarshalIndent(repo, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal repository: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// lockFile takes an exclusive lock by creating path, waiting for other
+// holders and breaking locks left behind by crashed processes
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock repository: %w", err)
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("repository is locked by another push (%s)", path)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,169 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"todo-cli/models"
+)
+
+// sshShim installs a fake ssh that records its arguments and runs the remote
+// command locally, with the test binary as the todo-cli helper. It returns
+// the file holding the recorded arguments.
+func sshShim(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+	shim := filepath.Join(dir, "ssh")
+	script := `#!/bin/sh
+printf '%s\n' "$@" > "$SSH_SHIM_ARGS"
+while [ "$1" != "--" ]; do shift; done
+shift 2
+exec sh -c "$1"
+`
+	if err := os.WriteFile(shim, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	helper, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TODO_SSH_COMMAND", shim)
+	t.Setenv("TODO_SSH_HELPER", ShellQuote(helper))
+	t.Setenv("TODO_TEST_SERVE_STDIO", "1")
+	t.Setenv("SSH_SHIM_ARGS", args)
+	return args
+}
+
+func TestSSHPushAndPull(t *testing.T) {
+	recorded := sshShim(t)
+	transport := &sshTransport{}
+
+	// A path the remote shell must not split or expand
+	path := filepath.Join(t.TempDir(), "it's $HOME", "repo.json")
+	remote := models.Remote{
+		Name: "box",
+		URL:  (&url.URL{Scheme: "ssh", User: url.User("alice"), Host: "example.com:2222", Path: path}).String(),
+		Type: "ssh",
+	}
+
+	if _, err := transport.Pull(remote); err == nil || !strings.Contains(err.Error(), "push first") {
+		t.Errorf("pull before push = %v, want a missing repository error", err)
+	}
+
+	if err := transport.Push(remote, testRepository(), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("repository was not written to %s: %v", path, err)
+	}
+
+	args, _ := os.ReadFile(recorded)
+	if lines := strings.Split(string(args), "\n"); len(lines) < 4 || strings.Join(lines[:4], " ") != "-p 2222 -- alice@example.com" {
+		t.Errorf("ssh arguments = %q", args)
+	}
+
+	pulled, err := transport.Pull(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pulled.Commits) != 1 || pulled.Branches[0].Todos[0].Title != "Ship" {
+		t.Errorf("pulled = %+v", pulled)
+	}
+
+	other := testRepository()
+	other.Commits = nil
+	if err := transport.Push(remote, other, false); err == nil || !strings.Contains(err.Error(), "push rejected") {
+		t.Errorf("push without the remote commits = %v, want rejected", err)
+	}
+	if err := transport.Push(remote, other, true); err != nil {
+		t.Errorf("forced push = %v", err)
+	}
+}
+
+func TestSSHCommandRejectsOptionLikeHosts(t *testing.T) {
+	t.Setenv("TODO_SSH_HELPER", "")
+	for _, rawURL := range []string{
+		"ssh://-oProxyCommand=id/srv/repo.json",
+		"ssh://-oProxyCommand=id@example.com/srv/repo.json",
+	} {
+		if _, err := sshCommand(context.Background(), models.Remote{URL: rawURL}); err == nil {
+			t.Errorf("sshCommand(%q) succeeded", rawURL)
+		}
+	}
+
+	cmd, err := sshCommand(context.Background(), models.Remote{URL: "ssh://example.com/~/todos.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(cmd.Args[len(cmd.Args)-3:], " "); got != "-- example.com todo-cli serve-stdio ~/'todos.json'" {
+		t.Errorf("ssh arguments end with %q", got)
+	}
+}
+
+func TestSSHTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not installed")
+	}
+	// An ssh that never answers
+	shim := filepath.Join(t.TempDir(), "ssh")
+	if err := os.WriteFile(shim, []byte("#!/bin/sh\nexec sleep 30\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TODO_SSH_COMMAND", shim)
+
+	start := time.Now()
+	_, err := (&sshTransport{}).Pull(models.Remote{Name: "box", URL: "ssh://example.com/srv/repo.json", Type: "ssh", Timeout: "200ms"})
+	if err == nil || !IsTransient(err) || !strings.Contains(err.Error(), "no response") {
+		t.Errorf("pull from a hanging ssh = %v, want a transient timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("pull took %s", elapsed)
+	}
+}
+
+func TestServeStdioPushKeepsDeletions(t *testing.T) {
+	r := NewRemoteService()
+	path := filepath.Join(t.TempDir(), "repo.json")
+	push := func(repo *models.Repository) {
+		t.Helper()
+		input, _ := json.Marshal(stdioRequest{Command: "push", Repository: repo})
+		var out bytes.Buffer
+		if err := r.ServeStdio(path, bytes.NewReader(append(input, '\n')), &out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), `"status":200`) {
+			t.Fatalf("push response = %s", out.String())
+		}
+	}
+
+	repo := testRepository()
+	repo.Branches[0].Todos = append(repo.Branches[0].Todos, models.Todo{ID: 2, Title: "Drop me", Status: "pending", BranchName: "main"})
+	repo.Branches = append(repo.Branches, models.Branch{Name: "feature"})
+	push(repo)
+
+	// Delete the feature branch and todo #2, then push again
+	repo.Branches = repo.Branches[:1]
+	repo.Branches[0].Todos = repo.Branches[0].Todos[:1]
+	push(repo)
+
+	stored, err := readRepositoryFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Branches) != 1 || len(stored.Branches[0].Todos) != 1 {
+		t.Errorf("stored = %+v, want the deleted branch and todo gone", stored.Branches)
+	}
+}
@@ -0,0 +1,247 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"todo-cli/models"
+)
+
+// Transport moves repositories to and from one type of remote. Transports are
+// registered by URL scheme, which is also the remote type stored in
+// models.Remote.Type.
+type Transport interface {
+	// Push stores repo on the remote. Force overwrites remote changes that
+	// are missing locally.
+	Push(remote models.Remote, repo *models.Repository, force bool) error
+	// Pull reads the repository to merge into the local one
+	Pull(remote models.Remote) (*models.Repository, error)
+	// Fetch reads the repository for inspection only; it must not change
+	// anything on the remote
+	Fetch(remote models.Remote) (*models.Repository, error)
+	// Capabilities describes the optional features of the transport
+	Capabilities() Capabilities
+}
+
+// Capabilities lists the optional features a transport supports
+type Capabilities struct {
+	Force    bool `json:"force"`    // Push honors force instead of always overwriting
+	Rejects  bool `json:"rejects"`  // Push refuses to drop remote commits missing locally
+	Events   bool `json:"events"`   // The remote has an event stream for todo watch
+	External bool `json:"external"` // Provided by a todo-remote-<scheme> executable
+}
+
+// externalPrefix names executables providing transports for unknown schemes
+const externalPrefix = "todo-remote-"
+
+var schemePattern = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+
+// RegisterTransport makes a transport available for a URL scheme, replacing
+// any transport registered for it before
+func (r *RemoteService) RegisterTransport(scheme string, transport Transport) {
+	r.transports[strings.ToLower(scheme)] = transport
+}
+
+// TransportTypes returns the registered remote types in order
+func (r *RemoteService) TransportTypes() []string {
+	types := make([]string, 0, len(r.transports))
+	for scheme := range r.transports {
+		types = append(types, scheme)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Transport returns the transport for a remote type, falling back to a
+// todo-remote-<type> executable on PATH
+func (r *RemoteService) Transport(remoteType string) (Transport, error) {
+	remoteType = strings.ToLower(remoteType)
+	if transport, ok := r.transports[remoteType]; ok {
+		return transport, nil
+	}
+
+	if schemePattern.MatchString(remoteType) {
+		if path, err := exec.LookPath(externalPrefix + remoteType); err == nil {
+			return &externalTransport{path: path}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported remote type: %s (available: %s, or install %s%s)",
+		remoteType, strings.Join(r.TransportTypes(), ", "), externalPrefix, remoteType)
+}
+
+// InferType guesses the remote type from a URL: paths ending in .git are git
+// remotes, URLs with a scheme use it (https is served by the http transport)
+// and anything else is a file path
+func InferType(rawURL string) string {
+	base, _, _ := strings.Cut(rawURL, "#")
+	if strings.HasSuffix(strings.TrimSuffix(base, "/"), ".git") {
+		return "git"
+	}
+
+	scheme, _, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		return "file"
+	}
+	scheme = strings.ToLower(scheme)
+	if scheme == "https" {
+		return "http"
+	}
+	return scheme
+}
+
+// httpTransport talks to a todo-cli server
+type httpTransport struct {
+	service *RemoteService
+}
+
+func (t *httpTransport) Push(remote models.Remote, repo *models.Repository, force bool) error {
+	return t.service.pushHTTP(remote, repo, force)
+}
+
+func (t *httpTransport) Pull(remote models.Remote) (*models.Repository, error) {
+	return t.service.pullHTTP(remote)
+}
+
+func (t *httpTransport) Fetch(remote models.Remote) (*models.Repository, error) {
+	return t.service.pullHTTP(remote)
+}
+
+func (t *httpTransport) Capabilities() Capabilities {
+	return Capabilities{Force: true, Rejects: true, Events: true}
+}
+
+// fileTransport reads and writes a JSON file or bundle
+type fileTransport struct {
+	service *RemoteService
+}
+
+func (t *fileTransport) Push(remote models.Remote, repo *models.Repository, force bool) error {
+	return t.service.pushFile(remote, repo)
+}
+
+func (t *fileTransport) Pull(remote models.Remote) (*models.Repository, error) {
+	return t.service.pullFile(remote)
+}
+
+func (t *fileTransport) Fetch(remote models.Remote) (*models.Repository, error) {
+	return t.service.pullFile(remote)
+}
+
+func (t *fileTransport) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+// gitTransport stores the repository on a branch of a git repository
+type gitTransport struct {
+	service *RemoteService
+}
+
+func (t *gitTransport) Push(remote models.Remote, repo *models.Repository, force bool) error {
+	return t.service.pushGit(remote, repo, force)
+}
+
+func (t *gitTransport) Pull(remote models.Remote) (*models.Repository, error) {
+	return t.service.pullGit(remote)
+}
+
+func (t *gitTransport) Fetch(remote models.Remote) (*models.Repository, error) {
+	return t.service.pullGit(remote)
+}
+
+func (t *gitTransport) Capabilities() Capabilities {
+	return Capabilities{Force: true, Rejects: true}
+}
+
+// externalTransport runs a todo-remote-<scheme> executable. The executable
+// gets the command ("push", "pull", "fetch" or "capabilities") as its
+// argument and an externalRequest as JSON on stdin. Pull and fetch print the
+// repository as JSON on stdout, capabilities prints a Capabilities object.
+// A non-zero exit status fails the command with stderr as the message.
+type externalTransport struct {
+	path string
+}
+
+// externalRequest is the JSON document sent to external transports
+type externalRequest struct {
+	Remote     models.Remote      `json:"remote"`
+	Repository *models.Repository `json:"repository,omitempty"`
+	Force      bool               `json:"force,omitempty"`
+}
+
+// run executes the transport and returns its stdout, killing it once the
+// remote's timeout passes
+func (t *externalTransport) run(command string, request externalRequest) ([]byte, error) {
+	timeout, err := RemoteTimeout(request.Remote)
+	if err != nil {
+		return nil, err
+	}
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.path, command)
+	// Do not wait for processes the transport left holding its output
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, &transientError{fmt.Errorf("%s %s: no response within %s", t.path, command, timeout)}
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s", message)
+		}
+		return nil, fmt.Errorf("%s %s failed: %w", t.path, command, err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func (t *externalTransport) Push(remote models.Remote, repo *models.Repository, force bool) error {
+	_, err := t.run("push", externalRequest{Remote: remote, Repository: repo, Force: force})
+	return err
+}
+
+func (t *externalTransport) Pull(remote models.Remote) (*models.Repository, error) {
+	return t.read("pull", remote)
+}
+
+func (t *externalTransport) Fetch(remote models.Remote) (*models.Repository, error) {
+	return t.read("fetch", remote)
+}
+
+func (t *externalTransport) read(command string, remote models.Remote) (*models.Repository, error) {
+	output, err := t.run(command, externalRequest{Remote: remote})
+	if err != nil {
+		return nil, err
+	}
+
+	var repo models.Repository
+	if err := json.Unmarshal(output, &repo); err != nil {
+		return nil, fmt.Errorf("failed to decode repository from %s: %w", t.path, err)
+	}
+	return &repo, nil
+}
+
+// Capabilities asks the executable; one that does not answer supports none
+func (t *externalTransport) Capabilities() Capabilities {
+	capabilities := Capabilities{}
+	if output, err := t.run("capabilities", externalRequest{}); err == nil {
+		json.Unmarshal(output, &capabilities)
+	}
+	capabilities.External = true
+	return capabilities
+}
@@ -0,0 +1,55 @@
+package remote
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"todo-cli/models"
+)
+
+func TestTransportTypeIsCaseInsensitive(t *testing.T) {
+	r := NewRemoteService()
+	for _, remoteType := range []string{"http", "HTTP", "Git", "S3"} {
+		if _, err := r.Transport(remoteType); err != nil {
+			t.Errorf("Transport(%q) = %v", remoteType, err)
+		}
+	}
+	if _, err := r.Transport("nonexistent"); err == nil {
+		t.Error("Transport(nonexistent) succeeded")
+	}
+}
+
+func TestInferType(t *testing.T) {
+	tests := map[string]string{
+		"https://todo.example.com":               "http",
+		"HTTP://todo.example.com":                "http",
+		"git@github.com:team/todos.git":          "git",
+		"https://github.com/team/todos.git#data": "git",
+		"s3://bucket/prefix":                     "s3",
+		"ssh://host/srv/todos.json":              "ssh",
+		"/media/usb/team.bundle":                 "file",
+	}
+	for url, want := range tests {
+		if got := InferType(url); got != want {
+			t.Errorf("InferType(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestExternalTransportTimesOut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo-remote-slow")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nsleep 10\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	transport := &externalTransport{path: path}
+
+	start := time.Now()
+	_, err := transport.Pull(models.Remote{Name: "slow", URL: "slow://host", Timeout: "200ms"})
+	if !IsTransient(err) {
+		t.Errorf("Pull = %v, want a transient timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Pull took %s, want it stopped at the timeout", elapsed)
+	}
+}
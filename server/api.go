@@ -0,0 +1,677 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"todo-cli/models"
+	"todo-cli/storage"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// apiError is returned by API operations to select the response status
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func errorf(status int, format string, args ...interface{}) *apiError {
+	return &apiError{status: status, message: fmt.Sprintf(format, args...)}
+}
+
+// Page is a paginated list response
+type Page struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// BranchInfo is the API representation of a branch without its todos
+type BranchInfo struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Parent    string    `json:"parent,omitempty"`
+	ForkPoint string    `json:"fork_point,omitempty"`
+	Todos     int       `json:"todos"`
+}
+
+// TodoInput is the request body for creating or patching a todo
+type TodoInput struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Status      *string `json:"status"`
+	Priority    *string `json:"priority"`
+}
+
+// BranchInput is the request body for creating or renaming a branch
+type BranchInput struct {
+	Name string `json:"name"`
+	From string `json:"from"` // Optional branch or commit to start from
+}
+
+// CommitInput is the request body for creating a commit or changing its message
+type CommitInput struct {
+	Message string `json:"message"`
+	Branch  string `json:"branch"` // Branch to commit on, the current branch by default
+}
+
+// handleDefaultAPI serves the resource endpoints of the default repository
+func (s *Server) handleDefaultAPI(w http.ResponseWriter, r *http.Request) {
+	s.handleAPI(defaultRepo, r.URL.EscapedPath())(w, r)
+}
+
+// handleAPI serves the resource endpoints of a repository. path is the
+// escaped request path relative to the repository, e.g. "branches/main/todos".
+func (s *Server) handleAPI(repoName, path string) http.HandlerFunc {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := range segments {
+		if unescaped, err := url.PathUnescape(segments[i]); err == nil {
+			segments[i] = unescaped
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Reads need read access, deleting branches or commits needs admin,
+		// everything else write
+		required := RoleWrite
+		switch {
+		case r.Method == http.MethodGet:
+			required = RoleRead
+		case r.Method == http.MethodDelete && (segments[0] == "branches" || segments[0] == "commits"):
+			required = RoleAdmin
+		}
+
+		s.withRepo(repoName, required, func(w http.ResponseWriter, r *http.Request) {
+			s.serveAPI(w, r, segments)
+		})(w, r)
+	}
+}
+
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request, segments []string) {
+	var result interface{}
+	status := http.StatusOK
+	var err error
+
+	switch {
+	case len(segments) == 1 && segments[0] == "branches":
+		switch r.Method {
+		case http.MethodGet:
+			result, err = s.read(r, func(repo *models.Repository) (interface{}, error) {
+				return listBranches(r, repo)
+			})
+		case http.MethodPost:
+			status = http.StatusCreated
+			result, err = s.write(r, func(repo *models.Repository) (interface{}, error) {
+				return createBranch(r, repo)
+			})
+		default:
+			err = errorf(http.StatusMethodNotAllowed, "method not allowed")
+		}
+	case len(segments) == 2 && segments[0] == "branches":
+		name := segments[1]
+		switch r.Method {
+		case http.MethodGet:
+			result, err = s.read(r, func(repo *models.Repository) (interface{}, error) {
+				branch := findBranch(repo, name)
+				if branch == nil {
+					return nil, errorf(http.StatusNotFound, "branch '%s' not found", name)
+				}
+				return branchInfo(*branch), nil
+			})
+		case http.MethodPatch:
+			result, err = s.write(r, func(repo *models.Repository) (interface{}, error) {
+				return renameBranch(r, repo, name)
+			})
+		case http.MethodDelete:
+			status = http.StatusNoContent
+			_, err = s.write(r, func(repo *models.Repository) (interface{}, error) {
+				return nil, deleteAPIBranch(r, repo, name)
+			})
+		default:
+			err = errorf(http.StatusMethodNotAllowed, "method not allowed")
+		}
+	case len(segments) == 3 && segments[0] == "branches" && segments[2] == "todos":
+		name := segments[1]
+		switch r.Method {
+		case http.MethodGet:
+			result, err = s.read(r, func(repo *models.Repository) (interface{}, error) {
+				branch := findBranch(repo, name)
+				if branch == nil {
+					return nil, errorf(http.StatusNotFound, "branch '%s' not found", name)
+				}
+				return listTodos(r, branch.Todos)
+			})
+		case http.MethodPost:
+			status = http.StatusCreated
+			result, err = s.write(r, func(repo *models.Repository) (interface{}, error) {
+				return createTodo(r, repo, name)
+			})
+		default:
+			err = errorf(http.StatusMethodNotAllowed, "method not allowed")
+		}
+	case len(segments) == 2 && segments[0] == "todos":
+		id, convErr := strconv.Atoi(segments[1])
+		if convErr != nil {
+			err = errorf(http.StatusBadRequest, "invalid todo ID '%s'", segments[1])
+			break
+		}
+		branchName := r.URL.Query().Get("branch")
+		switch r.Method {
+		case http.MethodGet:
+			result, err = s.read(r, func(repo *models.Repository) (interface{}, error) {
+				todo, err := findTodo(repo, id, branchName)
+				if err != nil {
+					return nil, err
+				}
+				return *todo, nil
+			})
+		case http.MethodPatch:
+			result, err = s.write(r, func(repo *models.Repository) (interface{}, error) {
+				return patchTodo(r, repo, id, branchName)
+			})
+		case http.MethodDelete:
+			status = http.StatusNoContent
+			_, err = s.write(r, func(repo *models.Repository) (interface{}, error) {
+				return nil, deleteTodo(repo, id, branchName)
+			})
+		default:
+			err = errorf(http.StatusMethodNotAllowed, "method not allowed")
+		}
+	case len(segments) == 1 && segments[0] == "commits":
+		switch r.Method {
+		case http.MethodGet:
+			result, err = s.read(r, func(repo *models.Repository) (interface{}, error) {
+				return listCommits(r, repo)
+			})
+		case http.MethodPost:
+			status = http.StatusCreated
+			result, err = s.write(r, func(repo *models.Repository) (interface{}, error) {
+				return createCommit(r, repo)
+			})
+		default:
+			err = errorf(http.StatusMethodNotAllowed, "method not allowed")
+		}
+	case len(segments) == 2 && segments[0] == "commits":
+		id := segments[1]
+		switch r.Method {
+		case http.MethodGet:
+			result, err = s.read(r, func(repo *models.Repository) (interface{}, error) {
+				for _, commit := range repo.Commits {
+					if commit.ID == id {
+						return commit, nil
+					}
+				}
+				return nil, errorf(http.StatusNotFound, "commit '%s' not found", id)
+			})
+		case http.MethodPatch:
+			result, err = s.write(r, func(repo *models.Repository) (interface{}, error) {
+				return rewordCommit(r, repo, id)
+			})
+		case http.MethodDelete:
+			status = http.StatusNoContent
+			_, err = s.write(r, func(repo *models.Repository) (interface{}, error) {
+				return nil, storageError(storage.DeleteCommit(repo, id))
+			})
+		default:
+			err = errorf(http.StatusMethodNotAllowed, "method not allowed")
+		}
+	default:
+		err = errorf(http.StatusNotFound, "not found")
+	}
+
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// read runs fn against the current state of the request's repository
+func (s *Server) read(r *http.Request, fn func(*models.Repository) (interface{}, error)) (interface{}, error) {
+	hosted := repoFromRequest(r)
+	lock := s.locks.For(hosted.Name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	repo, err := s.loadRepository(s.repoPath(hosted))
+	if err != nil {
+		return nil, errorf(http.StatusInternalServerError, "failed to load repository")
+	}
+
+	return fn(repo)
+}
+
+// write runs fn against the request's repository and saves the result,
+// publishing an update event to subscribers and webhooks
+func (s *Server) write(r *http.Request, fn func(*models.Repository) (interface{}, error)) (interface{}, error) {
+	hosted := repoFromRequest(r)
+	lock := s.locks.For(hosted.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	repo, err := s.loadRepository(s.repoPath(hosted))
+	if err != nil {
+		return nil, errorf(http.StatusInternalServerError, "failed to load repository")
+	}
+	// Keep a deep enough copy to summarize the change in the event
+	previous := *repo
+	previous.Commits = append([]models.Commit{}, repo.Commits...)
+	previous.Branches = make([]models.Branch, len(repo.Branches))
+	for i, branch := range repo.Branches {
+		branch.Todos = append([]models.Todo{}, branch.Todos...)
+		previous.Branches[i] = branch
+	}
+
+	result, err := fn(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveRepository(hosted, repo); err != nil {
+		return nil, errorf(http.StatusInternalServerError, "failed to save repository")
+	}
+	s.publish("update", hosted.Name, requestUser(r), &previous, repo)
+
+	return result, nil
+}
+
+// writeAPIError writes an error as a JSON body
+func writeAPIError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if apiErr, ok := err.(*apiError); ok {
+		status = apiErr.status
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// paginate applies the limit and offset query parameters to n items,
+// returning the bounds of the requested page
+func paginate(r *http.Request, n int) (start, end, limit int, err error) {
+	limit = defaultPageSize
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, 0, 0, errorf(http.StatusBadRequest, "limit must be between 1 and %d", maxPageSize)
+		}
+	}
+
+	offset := 0
+	if value := r.URL.Query().Get("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, 0, errorf(http.StatusBadRequest, "offset must be a non-negative integer")
+		}
+	}
+
+	start = min(offset, n)
+	end = min(start+limit, n)
+	return start, end, limit, nil
+}
+
+func decodeBody(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errorf(http.StatusBadRequest, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+func findBranch(repo *models.Repository, name string) *models.Branch {
+	for i := range repo.Branches {
+		if repo.Branches[i].Name == name {
+			return &repo.Branches[i]
+		}
+	}
+	return nil
+}
+
+func branchInfo(branch models.Branch) BranchInfo {
+	return BranchInfo{
+		Name:      branch.Name,
+		CreatedAt: branch.CreatedAt,
+		Parent:    branch.Parent,
+		ForkPoint: branch.ForkPoint,
+		Todos:     len(branch.Todos),
+	}
+}
+
+func listBranches(r *http.Request, repo *models.Repository) (interface{}, error) {
+	start, end, limit, err := paginate(r, len(repo.Branches))
+	if err != nil {
+		return nil, err
+	}
+
+	items := []BranchInfo{}
+	for _, branch := range repo.Branches[start:end] {
+		items = append(items, branchInfo(branch))
+	}
+
+	return Page{Items: items, Total: len(repo.Branches), Limit: limit, Offset: start}, nil
+}
+
+func createBranch(r *http.Request, repo *models.Repository) (interface{}, error) {
+	var input BranchInput
+	if err := decodeBody(r, &input); err != nil {
+		return nil, err
+	}
+
+	branch, err := storage.CreateBranch(repo, input.Name, input.From)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	return branchInfo(*branch), nil
+}
+
+// renameBranch renames a branch like 'todo branch rename'; ?force=true
+// corresponds to --force
+func renameBranch(r *http.Request, repo *models.Repository, name string) (interface{}, error) {
+	var input BranchInput
+	if err := decodeBody(r, &input); err != nil {
+		return nil, err
+	}
+	if input.Name == "" {
+		input.Name = name
+	}
+
+	force := r.URL.Query().Get("force") == "true"
+	branch, err := storage.RenameBranch(repo, name, input.Name, force)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	return branchInfo(*branch), nil
+}
+
+// deleteAPIBranch deletes a branch like 'todo branch delete'; ?force=true
+// corresponds to --force
+func deleteAPIBranch(r *http.Request, repo *models.Repository, name string) error {
+	force := r.URL.Query().Get("force") == "true"
+	return storageError(storage.DeleteBranch(repo, name, force))
+}
+
+// storageError converts an error of a storage operation into an API error
+func storageError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, storage.ErrNotFound):
+		return errorf(http.StatusNotFound, "%s", err)
+	case errors.Is(err, storage.ErrExists), errors.Is(err, storage.ErrRefused):
+		return errorf(http.StatusConflict, "%s", err)
+	default:
+		return errorf(http.StatusInternalServerError, "%s", err)
+	}
+}
+
+// listTodos filters todos by the status, priority and q (title/description
+// substring) query parameters and paginates the result
+func listTodos(r *http.Request, todos []models.Todo) (interface{}, error) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	priority := query.Get("priority")
+	text := strings.ToLower(query.Get("q"))
+
+	if status != "" && !models.ValidStatus(status) {
+		return nil, errorf(http.StatusBadRequest, "status must be one of %s", strings.Join(models.TodoStatuses, ", "))
+	}
+	if priority != "" && !models.ValidPriority(priority) {
+		return nil, errorf(http.StatusBadRequest, "priority must be one of %s", strings.Join(models.TodoPriorities, ", "))
+	}
+
+	matched := []models.Todo{}
+	for _, todo := range todos {
+		if status != "" && todo.Status != status {
+			continue
+		}
+		if priority != "" && todo.Priority != priority {
+			continue
+		}
+		if text != "" && !strings.Contains(strings.ToLower(todo.Title+" "+todo.Description), text) {
+			continue
+		}
+		matched = append(matched, todo)
+	}
+
+	start, end, limit, err := paginate(r, len(matched))
+	if err != nil {
+		return nil, err
+	}
+
+	return Page{Items: matched[start:end], Total: len(matched), Limit: limit, Offset: start}, nil
+}
+
+// validateTodoInput checks the fields of a todo request like the CLI does
+func validateTodoInput(input TodoInput) error {
+	if input.Title != nil && strings.TrimSpace(*input.Title) == "" {
+		return errorf(http.StatusBadRequest, "title must not be empty")
+	}
+	if input.Status != nil && !models.ValidStatus(*input.Status) {
+		return errorf(http.StatusBadRequest, "status must be one of %s", strings.Join(models.TodoStatuses, ", "))
+	}
+	if input.Priority != nil && !models.ValidPriority(*input.Priority) {
+		return errorf(http.StatusBadRequest, "priority must be one of %s", strings.Join(models.TodoPriorities, ", "))
+	}
+	return nil
+}
+
+func createTodo(r *http.Request, repo *models.Repository, branchName string) (interface{}, error) {
+	var input TodoInput
+	if err := decodeBody(r, &input); err != nil {
+		return nil, err
+	}
+	if input.Title == nil {
+		return nil, errorf(http.StatusBadRequest, "title is required")
+	}
+	if err := validateTodoInput(input); err != nil {
+		return nil, err
+	}
+
+	branch := findBranch(repo, branchName)
+	if branch == nil {
+		return nil, errorf(http.StatusNotFound, "branch '%s' not found", branchName)
+	}
+
+	// Repositories pushed by older clients may lack the counter
+	if repo.NextTodoID < 1 {
+		repo.NextTodoID = 1
+		for _, b := range repo.Branches {
+			for _, t := range b.Todos {
+				repo.NextTodoID = max(repo.NextTodoID, t.ID+1)
+			}
+		}
+	}
+
+	todo := models.Todo{
+		ID:         repo.NextTodoID,
+		Title:      *input.Title,
+		Status:     "pending",
+		Priority:   "medium",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		BranchName: branch.Name,
+	}
+	if input.Description != nil {
+		todo.Description = *input.Description
+	}
+	if input.Status != nil {
+		todo.Status = *input.Status
+	}
+	if input.Priority != nil {
+		todo.Priority = *input.Priority
+	}
+
+	branch.Todos = append(branch.Todos, todo)
+	repo.NextTodoID++
+
+	return todo, nil
+}
+
+// findTodo returns a todo by ID. Copied branches share todo IDs, so when the
+// ID exists on several branches the branch must be given explicitly.
+func findTodo(repo *models.Repository, id int, branchName string) (*models.Todo, error) {
+	var found *models.Todo
+	var branches []string
+
+	for i := range repo.Branches {
+		if branchName != "" && repo.Branches[i].Name != branchName {
+			continue
+		}
+		for j := range repo.Branches[i].Todos {
+			if repo.Branches[i].Todos[j].ID == id {
+				found = &repo.Branches[i].Todos[j]
+				branches = append(branches, repo.Branches[i].Name)
+			}
+		}
+	}
+
+	switch len(branches) {
+	case 0:
+		return nil, errorf(http.StatusNotFound, "todo #%d not found", id)
+	case 1:
+		return found, nil
+	default:
+		return nil, errorf(http.StatusConflict, "todo #%d exists on branches %s, specify ?branch=", id, strings.Join(branches, ", "))
+	}
+}
+
+func patchTodo(r *http.Request, repo *models.Repository, id int, branchName string) (interface{}, error) {
+	var input TodoInput
+	if err := decodeBody(r, &input); err != nil {
+		return nil, err
+	}
+	if err := validateTodoInput(input); err != nil {
+		return nil, err
+	}
+
+	todo, err := findTodo(repo, id, branchName)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Title != nil {
+		todo.Title = *input.Title
+	}
+	if input.Description != nil {
+		todo.Description = *input.Description
+	}
+	if input.Status != nil {
+		todo.Status = *input.Status
+	}
+	if input.Priority != nil {
+		todo.Priority = *input.Priority
+	}
+	todo.UpdatedAt = time.Now()
+
+	return *todo, nil
+}
+
+func deleteTodo(repo *models.Repository, id int, branchName string) error {
+	todo, err := findTodo(repo, id, branchName)
+	if err != nil {
+		return err
+	}
+
+	for i := range repo.Branches {
+		for j := range repo.Branches[i].Todos {
+			if &repo.Branches[i].Todos[j] == todo {
+				branch := &repo.Branches[i]
+				branch.Todos = append(branch.Todos[:j], branch.Todos[j+1:]...)
+				return nil
+			}
+		}
+	}
+
+	return errorf(http.StatusNotFound, "todo #%d not found", id)
+}
+
+// createCommit commits the staged todos of a branch, or every completed todo
+// changed since its last commit, like 'todo commit create'
+func createCommit(r *http.Request, repo *models.Repository) (interface{}, error) {
+	var input CommitInput
+	if err := decodeBody(r, &input); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(input.Message) == "" {
+		return nil, errorf(http.StatusBadRequest, "message is required")
+	}
+	if input.Branch == "" {
+		input.Branch = repo.CurrentBranch
+	}
+
+	branch := findBranch(repo, input.Branch)
+	if branch == nil {
+		return nil, errorf(http.StatusNotFound, "branch '%s' not found", input.Branch)
+	}
+
+	snapshot := storage.CommitCandidates(repo, branch)
+	if len(snapshot) == 0 {
+		return nil, errorf(http.StatusBadRequest, "no completed todos changed since the last commit on '%s'", branch.Name)
+	}
+
+	commit := storage.NewCommit(repo, branch.Name, input.Message, requestUser(r), snapshot)
+	repo.Commits = append(repo.Commits, commit)
+	branch.Staged = nil
+
+	return commit, nil
+}
+
+// rewordCommit changes the message of a commit
+func rewordCommit(r *http.Request, repo *models.Repository, id string) (interface{}, error) {
+	var input CommitInput
+	if err := decodeBody(r, &input); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(input.Message) == "" {
+		return nil, errorf(http.StatusBadRequest, "message is required")
+	}
+
+	for i := range repo.Commits {
+		if repo.Commits[i].ID == id {
+			repo.Commits[i].Message = input.Message
+			return repo.Commits[i], nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "commit '%s' not found", id)
+}
+
+// listCommits lists commits newest first, optionally filtered by branch
+func listCommits(r *http.Request, repo *models.Repository) (interface{}, error) {
+	branchName := r.URL.Query().Get("branch")
+
+	matched := []models.Commit{}
+	for i := len(repo.Commits) - 1; i >= 0; i-- {
+		if branchName == "" || repo.Commits[i].Branch == branchName {
+			matched = append(matched, repo.Commits[i])
+		}
+	}
+
+	start, end, limit, err := paginate(r, len(matched))
+	if err != nil {
+		return nil, err
+	}
+
+	return Page{Items: matched[start:end], Total: len(matched), Limit: limit, Offset: start}, nil
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const usersFile = "server_users.json"
+
+// Roles, from least to most privileged
+const (
+	RoleRead  = "read"
+	RoleWrite = "write"
+	RoleAdmin = "admin"
+)
+
+var roleRank = map[string]int{
+	RoleRead:  1,
+	RoleWrite: 2,
+	RoleAdmin: 3,
+}
+
+// User is an account allowed to access the server
+type User struct {
+	Username     string   `json:"username"`
+	PasswordHash string   `json:"password_hash"` // bcrypt hash
+	Role         string   `json:"role"`          // "read", "write", "admin"
+	TokenHashes  []string `json:"token_hashes"`  // SHA-256 hashes of API tokens
+}
+
+// UserStore holds the server's accounts, loaded from a JSON users file
+type UserStore struct {
+	path  string
+	mu    sync.Mutex
+	Users []User `json:"users"`
+}
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// LoadUserStore loads the users file. It returns nil if the file does not
+// exist, which leaves the server open to anonymous access.
+func LoadUserStore(path string) (*UserStore, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+
+	store := &UserStore{path: path}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse users file: %w", err)
+	}
+
+	for _, user := range store.Users {
+		if _, ok := roleRank[user.Role]; !ok {
+			return nil, fmt.Errorf("user '%s' has unknown role '%s'", user.Username, user.Role)
+		}
+	}
+
+	return store, nil
+}
+
+// save writes the users file; callers must hold the lock
+func (u *UserStore) save() error {
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(u.path, data, 0600)
+}
+
+// dummyPasswordHash is compared against for unknown usernames, so a failed
+// login takes as long whether or not the user exists
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("todo-cli unknown user"), bcrypt.DefaultCost)
+	return hash
+})
+
+// Authenticate validates Basic or Bearer credentials on a request. Passwords
+// are checked outside the lock, so slow bcrypt comparisons do not make
+// concurrent requests wait for each other.
+func (u *UserStore) Authenticate(r *http.Request) (*User, error) {
+	if username, password, ok := r.BasicAuth(); ok {
+		user := u.find(func(candidate *User) bool { return candidate.Username == username })
+
+		hash := dummyPasswordHash()
+		if user != nil {
+			hash = []byte(user.PasswordHash)
+		}
+		if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || user == nil {
+			return nil, fmt.Errorf("invalid username or password")
+		}
+		return user, nil
+	}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		tokenHash := hashToken(token)
+		user := u.find(func(candidate *User) bool {
+			for _, hash := range candidate.TokenHashes {
+				if hash == tokenHash {
+					return true
+				}
+			}
+			return false
+		})
+		if user == nil {
+			return nil, fmt.Errorf("invalid API token")
+		}
+		return user, nil
+	}
+
+	return nil, fmt.Errorf("authentication required")
+}
+
+// find returns a copy of the first user matching match, or nil
+func (u *UserStore) find(match func(*User) bool) *User {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for i := range u.Users {
+		if match(&u.Users[i]) {
+			user := u.Users[i]
+			user.TokenHashes = append([]string(nil), user.TokenHashes...)
+			return &user
+		}
+	}
+	return nil
+}
+
+// AddUser creates or replaces a user with a bcrypt-hashed password
+func (u *UserStore) AddUser(username, password, role string) error {
+	if _, ok := roleRank[role]; !ok {
+		return fmt.Errorf("unknown role '%s' (use read, write or admin)", role)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for i := range u.Users {
+		if u.Users[i].Username == username {
+			u.Users[i].PasswordHash = string(hash)
+			u.Users[i].Role = role
+			return u.save()
+		}
+	}
+
+	u.Users = append(u.Users, User{Username: username, PasswordHash: string(hash), Role: role})
+	return u.save()
+}
+
+// IssueToken generates a new API token for a user and stores its hash
+func (u *UserStore) IssueToken(username string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for i := range u.Users {
+		if u.Users[i].Username == username {
+			u.Users[i].TokenHashes = append(u.Users[i].TokenHashes, hashToken(token))
+			if err := u.save(); err != nil {
+				return "", fmt.Errorf("failed to save users file: %w", err)
+			}
+			return token, nil
+		}
+	}
+
+	return "", fmt.Errorf("user '%s' not found", username)
+}
+
+// hashToken returns the hex SHA-256 of an API token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// roleAllows reports whether a role grants at least the required role
+func roleAllows(role, required string) bool {
+	return roleRank[role] >= roleRank[required]
+}
+
+// requireRole wraps a handler with authentication and a minimum role check
+func (s *Server) requireRole(required string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Without a users file every request acts as an anonymous admin
+		if s.users == nil {
+			next(w, r)
+			return
+		}
+
+		user, ok := s.authenticate(w, r)
+		if !ok {
+			return
+		}
+
+		if !roleAllows(user.Role, required) {
+			http.Error(w, fmt.Sprintf("Forbidden: user '%s' has role '%s', '%s' is required", user.Username, user.Role, required), http.StatusForbidden)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
+	}
+}
+
+// authenticate validates a request's credentials, writing a 401 response on failure
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (*User, bool) {
+	user, err := s.users.Authenticate(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="todo-cli"`)
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+	return user, true
+}
+
+// hasRole reports whether the authenticated user of a request has the given role
+func (s *Server) hasRole(r *http.Request, required string) bool {
+	if s.users == nil {
+		return true
+	}
+	user, ok := r.Context().Value(userContextKey).(*User)
+	return ok && roleAllows(user.Role, required)
+}
+
+// handleLogin exchanges Basic credentials for a new API token
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.users == nil {
+		http.Error(w, "Authentication is not enabled on this server", http.StatusNotFound)
+		return
+	}
+
+	if _, _, ok := r.BasicAuth(); !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="todo-cli"`)
+		http.Error(w, "Unauthorized: username and password required", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := s.users.Authenticate(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="todo-cli"`)
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	token, err := s.users.IssueToken(user.Username)
+	if err != nil {
+		http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"token": token})
+
+	fmt.Printf("Issued token for %s\n", user.Username)
+}
+
+// runUserCommand handles the server's user management subcommands
+func runUserCommand(path string, args []string) error {
+	store, err := LoadUserStore(path)
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		store = &UserStore{path: path}
+	}
+
+	switch args[0] {
+	case "adduser":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: adduser <username> <read|write|admin>")
+		}
+		password := os.Getenv("TODO_SERVER_PASSWORD")
+		if password == "" {
+			fmt.Print("Password: ")
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil {
+				return fmt.Errorf("failed to read password: %w", err)
+			}
+			password = strings.TrimSpace(line)
+		}
+		if password == "" {
+			return fmt.Errorf("password must not be empty")
+		}
+		if err := store.AddUser(args[1], password, args[2]); err != nil {
+			return err
+		}
+		fmt.Printf("Saved user '%s' (%s) to %s\n", args[1], args[2], path)
+	case "token":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: token <username>")
+		}
+		token, err := store.IssueToken(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(token)
+	default:
+		return fmt.Errorf("unknown command '%s' (use adduser or token)", args[0])
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// authTestServer returns a server with a reader, a writer and an admin, all
+// with the password "secret", and an API token for the reader
+func authTestServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	dataDir := t.TempDir()
+	users := &UserStore{path: filepath.Join(dataDir, usersFile)}
+	for name, role := range map[string]string{"reader": RoleRead, "writer": RoleWrite, "boss": RoleAdmin} {
+		if err := users.AddUser(name, "secret", role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, err := users.IssueToken("reader")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repos, err := LoadRepoRegistry(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhooks, err := LoadWebhookStore(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(dataDir, users, repos, webhooks, defaultHistoryLimit), token
+}
+
+func TestAuthenticationAndRoles(t *testing.T) {
+	s, token := authTestServer(t)
+	pull := s.withRepo(defaultRepo, RoleRead, s.handlePull)
+	push := s.withRepo(defaultRepo, RoleWrite, s.handlePush)
+	admin := s.requireRole(RoleAdmin, s.handleAdminRepos)
+
+	basic := func(user, password string) func(*http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(user, password) }
+	}
+	bearer := func(token string) func(*http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+	anonymous := func(*http.Request) {}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		auth    func(*http.Request)
+		want    int
+	}{
+		{"no credentials", pull, "GET", "/pull", anonymous, http.StatusUnauthorized},
+		{"wrong password", pull, "GET", "/pull", basic("reader", "wrong"), http.StatusUnauthorized},
+		{"unknown user", pull, "GET", "/pull", basic("nobody", "secret"), http.StatusUnauthorized},
+		{"unknown token", pull, "GET", "/pull", bearer("nope"), http.StatusUnauthorized},
+		{"reader pulls", pull, "GET", "/pull", basic("reader", "secret"), http.StatusOK},
+		{"reader pulls with token", pull, "GET", "/pull", bearer(token), http.StatusOK},
+		{"reader pushes", push, "POST", "/push", basic("reader", "secret"), http.StatusForbidden},
+		{"writer pushes", push, "POST", "/push", basic("writer", "secret"), http.StatusOK},
+		{"writer force pushes", push, "POST", "/push?force=true", basic("writer", "secret"), http.StatusForbidden},
+		{"admin force pushes", push, "POST", "/push?force=true", basic("boss", "secret"), http.StatusOK},
+		{"writer lists repositories", admin, "GET", "/admin/repos", basic("writer", "secret"), http.StatusForbidden},
+		{"admin lists repositories", admin, "GET", "/admin/repos", basic("boss", "secret"), http.StatusOK},
+		{"admin endpoint without credentials", admin, "GET", "/admin/repos", anonymous, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(`{"branches":[{"name":"main"}]}`))
+		tt.auth(req)
+		rec := httptest.NewRecorder()
+		tt.handler(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.name, rec.Code, tt.want, strings.TrimSpace(rec.Body.String()))
+		}
+		if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: 401 without WWW-Authenticate", tt.name)
+		}
+	}
+}
+
+func TestAuthenticateUnknownUserComparesHash(t *testing.T) {
+	s, _ := authTestServer(t)
+
+	req := httptest.NewRequest("GET", "/pull", nil)
+	req.SetBasicAuth("nobody", "secret")
+	if _, err := s.users.Authenticate(req); err == nil || err.Error() != "invalid username or password" {
+		t.Errorf("unknown user = %v", err)
+	}
+	if _, err := bcrypt.Cost(dummyPasswordHash()); err != nil {
+		t.Errorf("dummy hash for unknown users is not a bcrypt hash: %v", err)
+	}
+}
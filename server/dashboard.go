@@ -0,0 +1,90 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"todo-cli/models"
+)
+
+// dashboardCommits is how many of the latest commits the dashboard lists
+const dashboardCommits = 10
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>{{.Name}} - Todo CLI Server</title>
+</head>
+<body>
+    <h1>Repository {{.Name}}</h1>
+    <h2>Branches</h2>
+    <table>
+        <tr><th>Branch</th><th>Todos</th><th>Completed</th></tr>
+        {{range .Branches}}<tr><td>{{.Name}}</td><td>{{.Todos}}</td><td>{{.Completed}}</td></tr>
+        {{else}}<tr><td colspan="3">No branches pushed yet</td></tr>
+        {{end}}
+    </table>
+    <h2>Latest commits</h2>
+    <ul>
+        {{range .Commits}}<li><code>{{.ID}}</code> [{{.Branch}}] {{.Message}}{{if .Author}} by {{.Author}}{{end}}</li>
+        {{else}}<li>No commits yet</li>
+        {{end}}
+    </ul>
+    <p id="live">Connecting for live updates...</p>
+    <script>
+        // Same-origin requests carry the credentials the browser asked for
+        // when opening this page, so the stream needs no token
+        const events = new EventSource({{.EventsPath}});
+        events.onopen = () => { document.getElementById("live").textContent = "Live: the page refreshes when someone pushes"; };
+        events.onerror = () => { document.getElementById("live").textContent = "Live updates disconnected, retrying..."; };
+        for (const type of ["push", "restore"]) {
+            events.addEventListener(type, () => location.reload());
+        }
+    </script>
+</body>
+</html>
+`))
+
+// dashboardPage is the data of the dashboard template
+type dashboardPage struct {
+	Name       string
+	Branches   []models.BranchSummary
+	Commits    []models.CommitSummary
+	EventsPath string
+}
+
+// handleDashboard serves an HTML overview of a repository that reloads
+// itself from the repository's event stream. It sits behind the same
+// authentication as the stream: a browser asks for Basic credentials once
+// and sends them along with the page's own EventSource request, which cannot
+// carry a Bearer token.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hosted := repoFromRequest(r)
+	lock := s.locks.For(hosted.Name)
+	lock.RLock()
+	repo, err := s.loadRepository(s.repoPath(hosted))
+	lock.RUnlock()
+	if err != nil {
+		http.Error(w, "Failed to load repository", http.StatusInternalServerError)
+		return
+	}
+
+	page := dashboardPage{Name: hosted.Name, EventsPath: "/events"}
+	if hosted.Name != defaultRepo {
+		page.EventsPath = "/repos/" + url.PathEscape(hosted.Name) + "/events"
+	}
+	// The event summaries of the whole repository describe its current state
+	page.Branches = newEvent("", hosted.Name, "", &models.Repository{}, repo).Branches
+	for i := len(repo.Commits) - 1; i >= 0 && len(page.Commits) < dashboardCommits; i-- {
+		commit := repo.Commits[i]
+		page.Commits = append(page.Commits, models.CommitSummary{ID: commit.ID, Message: commit.Message, Branch: commit.Branch, Author: commit.Author})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	dashboardTemplate.Execute(w, page)
+}
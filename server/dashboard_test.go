@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDashboardUsesServerAuthentication(t *testing.T) {
+	s, _ := authTestServer(t)
+	dashboard := s.withRepo(defaultRepo, RoleRead, s.handleDashboard)
+
+	rec := httptest.NewRecorder()
+	dashboard(rec, httptest.NewRequest("GET", "/dashboard", nil))
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("anonymous dashboard = %d, want a Basic auth challenge", rec.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/dashboard", nil)
+	req.SetBasicAuth("reader", "secret")
+	rec = httptest.NewRecorder()
+	dashboard(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "new EventSource(") {
+		t.Errorf("dashboard = %d %s, want a page subscribing to events", rec.Code, rec.Body.String())
+	}
+}
+
+func TestEventsAllowNoCrossOriginAccess(t *testing.T) {
+	s, _ := authTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/events", nil).WithContext(ctx)
+	req.SetBasicAuth("reader", "secret")
+	req.Header.Set("Origin", "http://elsewhere.example")
+	rec := httptest.NewRecorder()
+
+	s.withRepo(defaultRepo, RoleRead, s.handleEvents)(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("events = %d", rec.Code)
+	}
+	if origin := rec.Header().Get("Access-Control-Allow-Origin"); origin != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want none", origin)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"todo-cli/models"
+)
+
+const eventKeepAlive = 30 * time.Second
+
+// EventBroker fans out repository events to server-sent event subscribers
+type EventBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan models.Event]bool
+}
+
+// Subscribe registers a channel for events of a repository
+func (b *EventBroker) Subscribe(repo string) chan models.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers == nil {
+		b.subscribers = make(map[string]map[chan models.Event]bool)
+	}
+	if b.subscribers[repo] == nil {
+		b.subscribers[repo] = make(map[chan models.Event]bool)
+	}
+
+	ch := make(chan models.Event, 16)
+	b.subscribers[repo][ch] = true
+	return ch
+}
+
+// Unsubscribe removes a channel registered with Subscribe
+func (b *EventBroker) Unsubscribe(repo string, ch chan models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscribers[repo], ch)
+}
+
+// Publish sends an event to every subscriber of its repository. Slow
+// subscribers miss events rather than blocking the publisher.
+func (b *EventBroker) Publish(event models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[event.Repository] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// newEvent builds an event describing the new state of a repository
+func newEvent(eventType, repoName, user string, previous, current *models.Repository) models.Event {
+	event := models.Event{
+		Type:       eventType,
+		Repository: repoName,
+		User:       user,
+		Time:       time.Now(),
+	}
+
+	for _, branch := range current.Branches {
+		summary := models.BranchSummary{Name: branch.Name, Todos: len(branch.Todos)}
+		for _, todo := range branch.Todos {
+			if todo.Status == "completed" {
+				summary.Completed++
+			}
+		}
+		event.Branches = append(event.Branches, summary)
+	}
+
+	known := make(map[string]bool)
+	for _, commit := range previous.Commits {
+		known[commit.ID] = true
+	}
+	for _, commit := range current.Commits {
+		if !known[commit.ID] {
+			event.Commits = append(event.Commits, models.CommitSummary{
+				ID:      commit.ID,
+				Message: commit.Message,
+				Branch:  commit.Branch,
+				Author:  commit.Author,
+			})
+		}
+	}
+
+	return event
+}
+
+// requestUser returns the name of the authenticated user of a request
+func requestUser(r *http.Request) string {
+	if user, ok := r.Context().Value(userContextKey).(*User); ok {
+		return user.Username
+	}
+	return ""
+}
+
+// handleEvents streams repository events as server-sent events
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	hosted := repoFromRequest(r)
+	events := s.events.Subscribe(hosted.Name)
+	defer s.events.Unsubscribe(hosted.Name, events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, ": subscribed to %s\n\n", hosted.Name)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
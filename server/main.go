@@ -2,28 +2,41 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"todo-cli/models"
 )
 
 const dataFile = "server_repository.json"
 
 type Server struct {
-	dataPath string
+	dataDir      string
+	users        *UserStore
+	repos        *RepoRegistry
+	locks        RepoLocks
+	events       EventBroker
+	webhooks     *WebhookStore
+	historyLimit int
 }
 
-func NewServer() *Server {
+func NewServer(dataDir string, users *UserStore, repos *RepoRegistry, webhooks *WebhookStore, historyLimit int) *Server {
 	return &Server{
-		dataPath: dataFile,
+		dataDir:      dataDir,
+		users:        users,
+		repos:        repos,
+		webhooks:     webhooks,
+		historyLimit: historyLimit,
 	}
 }
 
-func (s *Server) loadRepository() (*models.Repository, error) {
-	if _, err := os.Stat(s.dataPath); os.IsNotExist(err) {
+func (s *Server) loadRepository(path string) (*models.Repository, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
 		// Return empty repository if file doesn't exist
 		return &models.Repository{
 			Branches:      []models.Branch{},
@@ -34,7 +47,7 @@ func (s *Server) loadRepository() (*models.Repository, error) {
 		}, nil
 	}
 
-	data, err := os.ReadFile(s.dataPath)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -48,13 +61,19 @@ func (s *Server) loadRepository() (*models.Repository, error) {
 	return &repo, nil
 }
 
-func (s *Server) saveRepository(repo *models.Repository) error {
+// saveRepository archives the current state of a hosted repository and
+// atomically replaces it. Callers must hold the repository's write lock.
+func (s *Server) saveRepository(hosted HostedRepo, repo *models.Repository) error {
 	data, err := json.MarshalIndent(repo, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(s.dataPath, data, 0644)
+	if err := s.archiveVersion(hosted); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.repoPath(hosted), data, 0644)
 }
 
 func (s *Server) handlePush(w http.ResponseWriter, r *http.Request) {
@@ -76,25 +95,92 @@ func (s *Server) handlePush(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Pushes may only add to the server state unless an admin forces them
+	// Hold the write lock from the checks through the save so pushes cannot interleave
+	hosted := repoFromRequest(r)
+	lock := s.locks.For(hosted.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	serverRepo, err := s.loadRepository(s.repoPath(hosted))
+	if err != nil {
+		http.Error(w, "Failed to load repository", http.StatusInternalServerError)
+		return
+	}
+
+	force := r.URL.Query().Get("force") == "true"
+	if force && !s.hasRole(r, RoleAdmin) {
+		http.Error(w, "Forbidden: force push requires the 'admin' role", http.StatusForbidden)
+		return
+	}
+
+	if deleted := deletedBranches(serverRepo, &clientRepo); len(deleted) > 0 && !s.hasRole(r, RoleAdmin) {
+		http.Error(w, fmt.Sprintf("Forbidden: deleting branches (%s) requires the 'admin' role", strings.Join(deleted, ", ")), http.StatusForbidden)
+		return
+	}
+
+	if missing := missingCommits(serverRepo, &clientRepo); missing > 0 && !force {
+		http.Error(w, fmt.Sprintf("Rejected: remote has %d commits you do not have, pull first", missing), http.StatusConflict)
+		return
+	}
+
 	// Save the pushed repository
-	err = s.saveRepository(&clientRepo)
+	err = s.saveRepository(hosted, &clientRepo)
 	if err != nil {
 		http.Error(w, "Failed to save repository", http.StatusInternalServerError)
 		return
 	}
 
-	fmt.Printf("Received push: %d branches, %d commits\n", len(clientRepo.Branches), len(clientRepo.Commits))
+	s.publish("push", hosted.Name, requestUser(r), serverRepo, &clientRepo)
+
+	fmt.Printf("Received push to %s: %d branches, %d commits\n", hosted.Name, len(clientRepo.Branches), len(clientRepo.Commits))
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Push successful"))
 }
 
+// deletedBranches returns server branches that are missing from a pushed repository
+func deletedBranches(serverRepo, clientRepo *models.Repository) []string {
+	clientBranches := make(map[string]bool)
+	for _, branch := range clientRepo.Branches {
+		clientBranches[branch.Name] = true
+	}
+
+	var deleted []string
+	for _, branch := range serverRepo.Branches {
+		if !clientBranches[branch.Name] {
+			deleted = append(deleted, branch.Name)
+		}
+	}
+	return deleted
+}
+
+// missingCommits counts server commits that are missing from a pushed repository
+func missingCommits(serverRepo, clientRepo *models.Repository) int {
+	clientCommits := make(map[string]bool)
+	for _, commit := range clientRepo.Commits {
+		clientCommits[commit.ID] = true
+	}
+
+	missing := 0
+	for _, commit := range serverRepo.Commits {
+		if !clientCommits[commit.ID] {
+			missing++
+		}
+	}
+	return missing
+}
+
 func (s *Server) handlePull(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	repo, err := s.loadRepository()
+	hosted := repoFromRequest(r)
+	lock := s.locks.For(hosted.Name)
+	lock.RLock()
+	repo, err := s.loadRepository(s.repoPath(hosted))
+	lock.RUnlock()
 	if err != nil {
 		http.Error(w, "Failed to load repository", http.StatusInternalServerError)
 		return
@@ -103,11 +189,15 @@ func (s *Server) handlePull(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(repo)
 	
-	fmt.Printf("Served pull: %d branches, %d commits\n", len(repo.Branches), len(repo.Commits))
+	fmt.Printf("Served pull from %s: %d branches, %d commits\n", hosted.Name, len(repo.Branches), len(repo.Commits))
 }
 
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
-	repo, err := s.loadRepository()
+	hosted := repoFromRequest(r)
+	lock := s.locks.For(hosted.Name)
+	lock.RLock()
+	repo, err := s.loadRepository(s.repoPath(hosted))
+	lock.RUnlock()
 	if err != nil {
 		http.Error(w, "Failed to load repository", http.StatusInternalServerError)
 		return
@@ -125,16 +215,81 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file (PEM)")
+	tlsKey := flag.String("tls-key", "", "TLS private key file (PEM)")
+	tlsSelfSigned := flag.Bool("tls-self-signed", false, "Serve HTTPS with a generated self-signed certificate (development only)")
+	tlsClientCA := flag.String("tls-client-ca", "", "Require client certificates signed by this CA bundle (mutual TLS)")
+	flag.Parse()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	server := NewServer()
+	dataDir := os.Getenv("DATA_DIR")
+	if dataDir == "" {
+		dataDir = "."
+	}
+
+	usersPath := os.Getenv("USERS_FILE")
+	if usersPath == "" {
+		usersPath = usersFile
+	}
+
+	// User management: todo-server adduser <name> <role> | token <name>
+	if flag.NArg() > 0 {
+		if err := runUserCommand(usersPath, flag.Args()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	users, err := LoadUserStore(usersPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if users == nil {
+		fmt.Printf("Warning: %s not found, authentication is disabled\n", usersPath)
+	}
 
-	http.HandleFunc("/push", server.handlePush)
-	http.HandleFunc("/pull", server.handlePull)
-	http.HandleFunc("/status", server.handleStatus)
+	repos, err := LoadRepoRegistry(dataDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	historyLimit := defaultHistoryLimit
+	if limit := os.Getenv("HISTORY_LIMIT"); limit != "" {
+		historyLimit, err = strconv.Atoi(limit)
+		if err != nil || historyLimit < 0 {
+			log.Fatalf("invalid HISTORY_LIMIT %q", limit)
+		}
+	}
+
+	webhooks, err := LoadWebhookStore(dataDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := NewServer(dataDir, users, repos, webhooks, historyLimit)
+
+	// The top-level endpoints serve the default repository
+	http.HandleFunc("/push", server.withRepo(defaultRepo, RoleWrite, server.handlePush))
+	http.HandleFunc("/pull", server.withRepo(defaultRepo, RoleRead, server.handlePull))
+	http.HandleFunc("/status", server.withRepo(defaultRepo, RoleRead, server.handleStatus))
+	http.HandleFunc("/events", server.withRepo(defaultRepo, RoleRead, server.handleEvents))
+	http.HandleFunc("/dashboard", server.withRepo(defaultRepo, RoleRead, server.handleDashboard))
+	http.HandleFunc("/login", server.handleLogin)
+	http.HandleFunc("/branches", server.handleDefaultAPI)
+	http.HandleFunc("/branches/", server.handleDefaultAPI)
+	http.HandleFunc("/todos/", server.handleDefaultAPI)
+	http.HandleFunc("/commits", server.handleDefaultAPI)
+	http.HandleFunc("/commits/", server.handleDefaultAPI)
+	http.HandleFunc("/openapi.json", server.handleOpenAPI)
+	http.HandleFunc("/repos/", server.handleRepoRoutes)
+	http.HandleFunc("/admin/repos", server.requireRole(RoleAdmin, server.handleAdminRepos))
+	http.HandleFunc("/admin/repos/", server.requireRole(RoleAdmin, server.handleAdminRepo))
+	http.HandleFunc("/admin/webhooks", server.requireRole(RoleAdmin, server.handleAdminWebhooks))
+	http.HandleFunc("/admin/webhooks/", server.requireRole(RoleAdmin, server.handleAdminWebhook))
 
 	// Serve static files for web interface (optional)
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -152,6 +307,14 @@ func main() {
         <li><a href="/status">GET /status</a> - Repository status</li>
         <li>POST /push - Push repository</li>
         <li>GET /pull - Pull repository</li>
+        <li>GET /events - Server-sent events announcing pushes</li>
+        <li><a href="/dashboard">GET /dashboard</a> - Branches and latest commits, refreshed live from /events</li>
+        <li>POST /login - Exchange credentials for an API token</li>
+        <li>/branches, /branches/&lt;name&gt;/todos, /todos/&lt;id&gt;, /commits - REST API (see <a href="/openapi.json">/openapi.json</a>)</li>
+        <li>/repos/&lt;name&gt;/push, /repos/&lt;name&gt;/pull, /repos/&lt;name&gt;/status, /repos/&lt;name&gt;/events, /repos/&lt;name&gt;/dashboard - Named repositories</li>
+        <li>GET/POST /admin/repos, DELETE /admin/repos/&lt;name&gt; - Manage repositories (admin)</li>
+        <li>GET /admin/repos/&lt;name&gt;/history, POST /admin/repos/&lt;name&gt;/history/&lt;version&gt;/restore - Repository history (admin)</li>
+        <li>GET/POST /admin/webhooks, DELETE /admin/webhooks/&lt;id&gt;, GET /admin/webhooks/&lt;id&gt;/deliveries - Webhooks (admin)</li>
     </ul>
 </body>
 </html>
@@ -161,8 +324,26 @@ func main() {
 		}
 	})
 
-	fmt.Printf("Todo CLI server starting on port %s\n", port)
-	fmt.Printf("Access the server at: http://localhost:%s\n", port)
-	
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	tlsConfig, err := serverTLSConfig(*tlsCert, *tlsKey, *tlsSelfSigned, *tlsClientCA)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if tlsConfig == nil {
+		fmt.Printf("Todo CLI server starting on port %s\n", port)
+		fmt.Printf("Access the server at: http://localhost:%s\n", port)
+		log.Fatal(http.ListenAndServe(":"+port, nil))
+	}
+
+	fmt.Printf("Todo CLI server starting on port %s (TLS)\n", port)
+	fmt.Printf("Access the server at: https://localhost:%s\n", port)
+	if *tlsSelfSigned {
+		fmt.Printf("Using a self-signed certificate (development only), trust it with: --ca-cert %s\n", devCertFile)
+	}
+
+	httpServer := &http.Server{
+		Addr:      ":" + port,
+		TLSConfig: tlsConfig,
+	}
+	log.Fatal(httpServer.ListenAndServeTLS("", ""))
 }
@@ -0,0 +1,274 @@
+package main
+
+import "net/http"
+
+// openAPISpec describes the REST API. Paths are relative to the default
+// repository; every path is also served under /repos/{repo}.
+const openAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "Todo CLI Sync Server API",
+    "version": "1.0.0",
+    "description": "Read and modify branches, todos and commits of a hosted repository. Every path is also available under /repos/{repo}. GET requires the read role, DELETE /branches/{name} and DELETE /commits/{id} require admin and other writes require write."
+  },
+  "components": {
+    "securitySchemes": {
+      "basic": {"type": "http", "scheme": "basic"},
+      "bearer": {"type": "http", "scheme": "bearer"}
+    },
+    "parameters": {
+      "limit": {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 500, "default": 50}},
+      "offset": {"name": "offset", "in": "query", "schema": {"type": "integer", "minimum": 0, "default": 0}},
+      "branchName": {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}},
+      "todoID": {"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}},
+      "todoBranch": {"name": "branch", "in": "query", "description": "Required when the todo ID exists on several branches", "schema": {"type": "string"}}
+    },
+    "schemas": {
+      "Error": {
+        "type": "object",
+        "properties": {"error": {"type": "string"}}
+      },
+      "Branch": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string"},
+          "created_at": {"type": "string", "format": "date-time"},
+          "parent": {"type": "string"},
+          "fork_point": {"type": "string"},
+          "todos": {"type": "integer"}
+        }
+      },
+      "BranchInput": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "name": {"type": "string"},
+          "from": {"type": "string", "description": "Branch or commit to start from"}
+        }
+      },
+      "CommitInput": {
+        "type": "object",
+        "required": ["message"],
+        "properties": {
+          "message": {"type": "string"},
+          "branch": {"type": "string", "description": "Branch to commit on when creating, the current branch by default"}
+        }
+      },
+      "Todo": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer"},
+          "title": {"type": "string"},
+          "description": {"type": "string"},
+          "status": {"type": "string", "enum": ["pending", "in-progress", "completed"]},
+          "priority": {"type": "string", "enum": ["low", "medium", "high"]},
+          "created_at": {"type": "string", "format": "date-time"},
+          "updated_at": {"type": "string", "format": "date-time"},
+          "branch_name": {"type": "string"}
+        }
+      },
+      "TodoInput": {
+        "type": "object",
+        "properties": {
+          "title": {"type": "string"},
+          "description": {"type": "string"},
+          "status": {"type": "string", "enum": ["pending", "in-progress", "completed"]},
+          "priority": {"type": "string", "enum": ["low", "medium", "high"]}
+        }
+      },
+      "Commit": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "message": {"type": "string"},
+          "branch": {"type": "string"},
+          "author": {"type": "string"},
+          "created_at": {"type": "string", "format": "date-time"},
+          "todos": {"type": "array", "items": {"type": "integer"}},
+          "snapshot": {"type": "array", "items": {"$ref": "#/components/schemas/Todo"}},
+          "before": {"type": "array", "items": {"$ref": "#/components/schemas/Todo"}}
+        }
+      },
+      "Page": {
+        "type": "object",
+        "properties": {
+          "items": {"type": "array", "items": {}},
+          "total": {"type": "integer"},
+          "limit": {"type": "integer"},
+          "offset": {"type": "integer"}
+        }
+      }
+    },
+    "responses": {
+      "Error": {"description": "Error", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}}
+    }
+  },
+  "security": [{"basic": []}, {"bearer": []}],
+  "paths": {
+    "/branches": {
+      "get": {
+        "summary": "List branches",
+        "parameters": [{"$ref": "#/components/parameters/limit"}, {"$ref": "#/components/parameters/offset"}],
+        "responses": {
+          "200": {"description": "Branches", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Page"}}}},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "summary": "Create a branch",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BranchInput"}}}},
+        "responses": {
+          "201": {"description": "Created", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Branch"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/branches/{name}": {
+      "parameters": [{"$ref": "#/components/parameters/branchName"}],
+      "get": {
+        "summary": "Get a branch",
+        "responses": {
+          "200": {"description": "Branch", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Branch"}}}},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "patch": {
+        "summary": "Rename a branch",
+        "description": "Renaming main needs force=true, since the new name loses main's delete protection.",
+        "parameters": [{"name": "force", "in": "query", "schema": {"type": "boolean", "default": false}}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BranchInput"}}}},
+        "responses": {
+          "200": {"description": "Renamed", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Branch"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a branch and its commits",
+        "description": "The current branch and branches other branches were created from cannot be deleted. main and branches with todos missing from the current branch need force=true.",
+        "parameters": [{"name": "force", "in": "query", "schema": {"type": "boolean", "default": false}}],
+        "responses": {
+          "204": {"description": "Deleted"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/branches/{name}/todos": {
+      "parameters": [{"$ref": "#/components/parameters/branchName"}],
+      "get": {
+        "summary": "List the todos of a branch",
+        "parameters": [
+          {"name": "status", "in": "query", "schema": {"type": "string", "enum": ["pending", "in-progress", "completed"]}},
+          {"name": "priority", "in": "query", "schema": {"type": "string", "enum": ["low", "medium", "high"]}},
+          {"name": "q", "in": "query", "description": "Case-insensitive text in the title or description", "schema": {"type": "string"}},
+          {"$ref": "#/components/parameters/limit"},
+          {"$ref": "#/components/parameters/offset"}
+        ],
+        "responses": {
+          "200": {"description": "Todos", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Page"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "summary": "Add a todo to a branch",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TodoInput"}}}},
+        "responses": {
+          "201": {"description": "Created", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Todo"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/todos/{id}": {
+      "parameters": [{"$ref": "#/components/parameters/todoID"}, {"$ref": "#/components/parameters/todoBranch"}],
+      "get": {
+        "summary": "Get a todo",
+        "responses": {
+          "200": {"description": "Todo", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Todo"}}}},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "patch": {
+        "summary": "Update a todo",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TodoInput"}}}},
+        "responses": {
+          "200": {"description": "Updated", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Todo"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a todo",
+        "responses": {
+          "204": {"description": "Deleted"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/commits": {
+      "get": {
+        "summary": "List commits, newest first",
+        "parameters": [
+          {"name": "branch", "in": "query", "schema": {"type": "string"}},
+          {"$ref": "#/components/parameters/limit"},
+          {"$ref": "#/components/parameters/offset"}
+        ],
+        "responses": {
+          "200": {"description": "Commits", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Page"}}}},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "summary": "Commit the staged todos of a branch, or every completed todo changed since its last commit",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CommitInput"}}}},
+        "responses": {
+          "201": {"description": "Created", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Commit"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/commits/{id}": {
+      "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "get": {
+        "summary": "Get a commit",
+        "responses": {
+          "200": {"description": "Commit", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Commit"}}}},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "patch": {
+        "summary": "Change the message of a commit",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CommitInput"}}}},
+        "responses": {
+          "200": {"description": "Updated", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Commit"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "summary": "Delete the newest commit of a branch, leaving its todos uncommitted",
+        "responses": {
+          "204": {"description": "Deleted"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  }
+}
+`
+
+// handleOpenAPI serves the OpenAPI document of the REST API
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(openAPISpec))
+}
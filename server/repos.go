@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	reposFile   = "server_repos.json"
+	reposDir    = "repos"
+	defaultRepo = "default"
+)
+
+var repoNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+const repoContextKey contextKey = "repo"
+
+// HostedRepo is a named repository served under /repos/<name>
+type HostedRepo struct {
+	Name      string            `json:"name"`
+	File      string            `json:"file"` // Storage file, relative to the data directory
+	CreatedAt time.Time         `json:"created_at"`
+	Access    map[string]string `json:"access,omitempty"` // Username to role; empty means global roles apply
+}
+
+// RepoRegistry tracks the repositories hosted by the server
+type RepoRegistry struct {
+	path  string
+	mu    sync.Mutex
+	Repos []HostedRepo `json:"repos"`
+}
+
+// LoadRepoRegistry loads the registry, creating it with the default repository
+// (stored in the legacy server_repository.json) if it does not exist yet
+func LoadRepoRegistry(dataDir string) (*RepoRegistry, error) {
+	registry := &RepoRegistry{path: filepath.Join(dataDir, reposFile)}
+
+	data, err := os.ReadFile(registry.path)
+	if os.IsNotExist(err) {
+		registry.Repos = []HostedRepo{{Name: defaultRepo, File: dataFile, CreatedAt: time.Now()}}
+		return registry, registry.save()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository registry: %w", err)
+	}
+
+	if err := json.Unmarshal(data, registry); err != nil {
+		return nil, fmt.Errorf("failed to parse repository registry: %w", err)
+	}
+
+	return registry, nil
+}
+
+// save writes the registry; callers must hold the lock or own the registry
+func (g *RepoRegistry) save() error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(g.path, data, 0644)
+}
+
+// Get returns a copy of a hosted repository by name
+func (g *RepoRegistry) Get(name string) (HostedRepo, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, repo := range g.Repos {
+		if repo.Name == name {
+			return repo, true
+		}
+	}
+	return HostedRepo{}, false
+}
+
+// List returns a copy of all hosted repositories
+func (g *RepoRegistry) List() []HostedRepo {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]HostedRepo{}, g.Repos...)
+}
+
+// Create registers a new repository
+func (g *RepoRegistry) Create(name string, access map[string]string) (HostedRepo, error) {
+	if !repoNamePattern.MatchString(name) {
+		return HostedRepo{}, fmt.Errorf("invalid repository name '%s'", name)
+	}
+	for user, role := range access {
+		if _, ok := roleRank[role]; !ok {
+			return HostedRepo{}, fmt.Errorf("unknown role '%s' for user '%s'", role, user)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, repo := range g.Repos {
+		if repo.Name == name {
+			return HostedRepo{}, fmt.Errorf("repository '%s' already exists", name)
+		}
+	}
+
+	repo := HostedRepo{
+		Name:      name,
+		File:      filepath.Join(reposDir, name+".json"),
+		CreatedAt: time.Now(),
+		Access:    access,
+	}
+	g.Repos = append(g.Repos, repo)
+
+	return repo, g.save()
+}
+
+// Delete unregisters a repository and removes its storage file
+func (g *RepoRegistry) Delete(dataDir, name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for i, repo := range g.Repos {
+		if repo.Name != name {
+			continue
+		}
+		g.Repos = append(g.Repos[:i], g.Repos[i+1:]...)
+		if err := g.save(); err != nil {
+			return err
+		}
+		if err := os.Remove(filepath.Join(dataDir, repo.File)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	return fmt.Errorf("repository '%s' not found", name)
+}
+
+// repoPath returns the storage file of a hosted repository
+func (s *Server) repoPath(repo HostedRepo) string {
+	return filepath.Join(s.dataDir, repo.File)
+}
+
+// repoFromRequest returns the hosted repository a request was routed to
+func repoFromRequest(r *http.Request) HostedRepo {
+	repo, _ := r.Context().Value(repoContextKey).(HostedRepo)
+	return repo
+}
+
+// withRepo routes a handler to a hosted repository and checks the user's
+// role on it. Users listed in the repository's access list get that role,
+// global admins always keep admin, and other users fall back to their global
+// role only when the repository has no access list.
+func (s *Server) withRepo(name, required string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		repo, ok := s.repos.Get(name)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Repository '%s' not found", name), http.StatusNotFound)
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), repoContextKey, repo))
+
+		if s.users == nil {
+			next(w, r)
+			return
+		}
+
+		user, ok := s.authenticate(w, r)
+		if !ok {
+			return
+		}
+
+		effective := *user
+		if user.Role != RoleAdmin && len(repo.Access) > 0 {
+			effective.Role = repo.Access[user.Username]
+		}
+
+		if !roleAllows(effective.Role, required) {
+			http.Error(w, fmt.Sprintf("Forbidden: user '%s' needs the '%s' role on repository '%s'", user.Username, required, repo.Name), http.StatusForbidden)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), userContextKey, &effective)))
+	}
+}
+
+// handleRepoRoutes dispatches /repos/<name>/<action> requests
+func (s *Server) handleRepoRoutes(w http.ResponseWriter, r *http.Request) {
+	name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/repos/"), "/")
+
+	switch action {
+	case "push":
+		s.withRepo(name, RoleWrite, s.handlePush)(w, r)
+	case "pull":
+		s.withRepo(name, RoleRead, s.handlePull)(w, r)
+	case "status", "":
+		s.withRepo(name, RoleRead, s.handleStatus)(w, r)
+	case "events":
+		s.withRepo(name, RoleRead, s.handleEvents)(w, r)
+	case "dashboard":
+		s.withRepo(name, RoleRead, s.handleDashboard)(w, r)
+	case "login":
+		s.handleLogin(w, r)
+	default:
+		resource, _, _ := strings.Cut(action, "/")
+		if resource == "branches" || resource == "todos" || resource == "commits" {
+			// Use the escaped path so branch names may contain slashes
+			_, escaped, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/repos/"), "/")
+			s.handleAPI(name, escaped)(w, r)
+			return
+		}
+		http.NotFound(w, r)
+	}
+}
+
+// handleAdminRepos lists (GET) and creates (POST) hosted repositories
+func (s *Server) handleAdminRepos(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.repos.List())
+	case http.MethodPost:
+		var request struct {
+			Name   string            `json:"name"`
+			Access map[string]string `json:"access"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Failed to parse request", http.StatusBadRequest)
+			return
+		}
+
+		repo, err := s.repos.Create(request.Name, request.Access)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fmt.Printf("Created repository %s\n", repo.Name)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(repo)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminRepo handles /admin/repos/<name>[/history[/<version>/restore]]:
+// DELETE removes a repository, GET .../history lists its stored versions and
+// POST .../history/<version>/restore brings one of them back
+func (s *Server) handleAdminRepo(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/repos/"), "/")
+	name := parts[0]
+
+	switch {
+	case len(parts) == 1:
+		s.handleAdminDeleteRepo(w, r, name)
+	case len(parts) == 2 && parts[1] == "history":
+		s.handleAdminHistory(w, r, name)
+	case len(parts) == 4 && parts[1] == "history" && parts[3] == "restore":
+		s.handleAdminRestore(w, r, name, parts[2])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleAdminDeleteRepo(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if name == defaultRepo {
+		http.Error(w, "The default repository cannot be deleted", http.StatusBadRequest)
+		return
+	}
+
+	lock := s.locks.For(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := s.repos.Delete(s.dataDir, name); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	os.RemoveAll(s.historyPath(name))
+
+	fmt.Printf("Deleted repository %s\n", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleAdminHistory(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hosted, ok := s.repos.Get(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Repository '%s' not found", name), http.StatusNotFound)
+		return
+	}
+
+	history, err := s.ListHistory(hosted)
+	if err != nil {
+		http.Error(w, "Failed to list history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+func (s *Server) handleAdminRestore(w http.ResponseWriter, r *http.Request, name, version string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hosted, ok := s.repos.Get(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Repository '%s' not found", name), http.StatusNotFound)
+		return
+	}
+
+	repo, err := s.RestoreVersion(hosted, version)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	s.events.Publish(newEvent("restore", name, requestUser(r), repo, repo))
+
+	fmt.Printf("Restored repository %s to version %s\n", name, version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"restored": version,
+		"branches": len(repo.Branches),
+		"commits":  len(repo.Commits),
+	})
+}
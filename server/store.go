@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"todo-cli/models"
+)
+
+const (
+	historyDir          = "history"
+	defaultHistoryLimit = 20
+	versionFormat       = "20060102T150405.000000000Z"
+)
+
+// RepoLocks serializes access to each hosted repository
+type RepoLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.RWMutex
+}
+
+// For returns the lock of a repository, creating it on first use
+func (l *RepoLocks) For(name string) *sync.RWMutex {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.locks == nil {
+		l.locks = make(map[string]*sync.RWMutex)
+	}
+	if l.locks[name] == nil {
+		l.locks[name] = &sync.RWMutex{}
+	}
+	return l.locks[name]
+}
+
+// HistoryVersion describes a stored previous state of a repository
+type HistoryVersion struct {
+	Version  string    `json:"version"`
+	SavedAt  time.Time `json:"saved_at"`
+	Branches int       `json:"branches"`
+	Commits  int       `json:"commits"`
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place,
+// so readers never see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// historyPath returns the directory holding previous versions of a repository
+func (s *Server) historyPath(name string) string {
+	return filepath.Join(s.dataDir, historyDir, name)
+}
+
+// archiveVersion copies the current state of a repository into its history
+// and prunes the oldest versions beyond the retention limit. Callers must
+// hold the repository's write lock.
+func (s *Server) archiveVersion(hosted HostedRepo) error {
+	data, err := os.ReadFile(s.repoPath(hosted))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	version := time.Now().UTC().Format(versionFormat)
+	if err := writeFileAtomic(filepath.Join(s.historyPath(hosted.Name), version+".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to archive version: %w", err)
+	}
+
+	versions, err := s.historyVersions(hosted.Name)
+	if err != nil {
+		return err
+	}
+	for len(versions) > s.historyLimit {
+		if err := os.Remove(filepath.Join(s.historyPath(hosted.Name), versions[0]+".json")); err != nil {
+			return err
+		}
+		versions = versions[1:]
+	}
+
+	return nil
+}
+
+// historyVersions returns the stored version names of a repository, oldest first
+func (s *Server) historyVersions(name string) ([]string, error) {
+	entries, err := os.ReadDir(s.historyPath(name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if version, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			versions = append(versions, version)
+		}
+	}
+	sort.Strings(versions)
+
+	return versions, nil
+}
+
+// ListHistory describes the stored versions of a repository, newest first
+func (s *Server) ListHistory(hosted HostedRepo) ([]HistoryVersion, error) {
+	lock := s.locks.For(hosted.Name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	versions, err := s.historyVersions(hosted.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []HistoryVersion{}
+	for i := len(versions) - 1; i >= 0; i-- {
+		repo, err := s.loadRepository(filepath.Join(s.historyPath(hosted.Name), versions[i]+".json"))
+		if err != nil {
+			return nil, err
+		}
+		savedAt, _ := time.Parse(versionFormat, versions[i])
+		history = append(history, HistoryVersion{
+			Version:  versions[i],
+			SavedAt:  savedAt,
+			Branches: len(repo.Branches),
+			Commits:  len(repo.Commits),
+		})
+	}
+
+	return history, nil
+}
+
+// RestoreVersion replaces a repository with one of its stored versions. The
+// state being replaced is archived first, so a restore can itself be undone.
+func (s *Server) RestoreVersion(hosted HostedRepo, version string) (*models.Repository, error) {
+	if strings.ContainsAny(version, `/\`) || strings.HasPrefix(version, ".") {
+		return nil, fmt.Errorf("invalid version '%s'", version)
+	}
+
+	lock := s.locks.For(hosted.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	path := filepath.Join(s.historyPath(hosted.Name), version+".json")
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("version '%s' not found", version)
+	}
+
+	repo, err := s.loadRepository(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveRepository(hosted, repo); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
@@ -0,0 +1,540 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"todo-cli/models"
+)
+
+const (
+	webhooksFile       = "server_webhooks.json"
+	deliveryLogFile    = "_webhook_deliveries.json" // In the history directory; repository names cannot start with "_"
+	maxDeliveryLog     = 500
+	webhookMaxAttempts = 5
+	webhookRetryDelay  = 2 * time.Second
+	webhookTimeout     = 10 * time.Second
+)
+
+// Webhook event types
+const (
+	WebhookPush              = "push"
+	WebhookBranchCreated     = "branch.created"
+	WebhookBranchMerged      = "branch.merged"
+	WebhookCommitCreated     = "commit.created"
+	WebhookTodoStatusChanged = "todo.status_changed"
+)
+
+var webhookEventTypes = []string{
+	WebhookPush,
+	WebhookBranchCreated,
+	WebhookBranchMerged,
+	WebhookCommitCreated,
+	WebhookTodoStatusChanged,
+}
+
+// Webhook is an outbound HTTP notification target
+type Webhook struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`     // Key for the X-Todo-Signature HMAC
+	Events     []string  `json:"events,omitempty"`     // Event types to send; empty means all
+	Repository string    `json:"repository,omitempty"` // Hosted repository to watch; empty means all
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// wants reports whether the webhook subscribes to an event of a repository
+func (h Webhook) wants(eventType, repo string) bool {
+	if h.Repository != "" && h.Repository != repo {
+		return false
+	}
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, event := range h.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPayload is the JSON body posted to a webhook
+type WebhookPayload struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	Repository string      `json:"repository"`
+	User       string      `json:"user,omitempty"`
+	Time       time.Time   `json:"time"`
+	Data       interface{} `json:"data"`
+}
+
+// TodoStatusChange is the data of a todo.status_changed event
+type TodoStatusChange struct {
+	Branch         string      `json:"branch"`
+	Todo           models.Todo `json:"todo"`
+	PreviousStatus string      `json:"previous_status"`
+}
+
+// BranchMerge is the data of a branch.merged event
+type BranchMerge struct {
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+	Commits int    `json:"commits"`
+}
+
+// WebhookDelivery records the outcome of sending one payload to one webhook
+type WebhookDelivery struct {
+	ID         string    `json:"id"`
+	WebhookID  string    `json:"webhook_id"`
+	Event      string    `json:"event"`
+	Repository string    `json:"repository"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Delivered  bool      `json:"delivered"`
+	Pending    bool      `json:"pending"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WebhookStore holds the configured webhooks and a bounded log of recent
+// deliveries, kept next to the repository history so it survives restarts
+type WebhookStore struct {
+	path       string
+	logPath    string
+	mu         sync.Mutex
+	Webhooks   []Webhook `json:"webhooks"`
+	deliveries []WebhookDelivery
+	client     *http.Client
+}
+
+// LoadWebhookStore loads the webhooks file and the delivery log from the data
+// directory
+func LoadWebhookStore(dataDir string) (*WebhookStore, error) {
+	store := &WebhookStore{
+		path:    filepath.Join(dataDir, webhooksFile),
+		logPath: filepath.Join(dataDir, historyDir, deliveryLogFile),
+		client:  &http.Client{Timeout: webhookTimeout},
+	}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read webhooks file: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, store); err != nil {
+			return nil, fmt.Errorf("failed to parse webhooks file: %w", err)
+		}
+	}
+
+	data, err = os.ReadFile(store.logPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read delivery log: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &store.deliveries); err != nil {
+			return nil, fmt.Errorf("failed to parse delivery log: %w", err)
+		}
+	}
+
+	// Payloads are not stored, so deliveries cut short by a restart cannot resume
+	for i := range store.deliveries {
+		if store.deliveries[i].Pending {
+			store.deliveries[i].Pending = false
+			store.deliveries[i].Error = "server stopped before the delivery finished"
+		}
+	}
+
+	return store, nil
+}
+
+// save writes the webhooks file; callers must hold the lock. It contains
+// the signing secrets, so only the server user may read it.
+func (w *WebhookStore) save() error {
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(w.path, data, 0600)
+}
+
+// saveLog writes the delivery log; callers must hold the lock. A log that
+// cannot be written is reported but does not stop deliveries.
+func (w *WebhookStore) saveLog() {
+	data, err := json.MarshalIndent(w.deliveries, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(w.logPath, data, 0644)
+	}
+	if err != nil {
+		fmt.Printf("Failed to save webhook delivery log: %v\n", err)
+	}
+}
+
+// List returns the webhooks with their secrets removed
+func (w *WebhookStore) List() []Webhook {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	hooks := []Webhook{}
+	for _, hook := range w.Webhooks {
+		hook.Secret = ""
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+// Create validates and registers a webhook
+func (w *WebhookStore) Create(hook Webhook) (Webhook, error) {
+	parsed, err := url.Parse(hook.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return Webhook{}, fmt.Errorf("webhook URL must be an http or https URL")
+	}
+	for _, event := range hook.Events {
+		known := false
+		for _, eventType := range webhookEventTypes {
+			known = known || event == eventType
+		}
+		if !known {
+			return Webhook{}, fmt.Errorf("unknown event '%s' (use %s)", event, strings.Join(webhookEventTypes, ", "))
+		}
+	}
+
+	hook.ID = randomID()
+	hook.CreatedAt = time.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.Webhooks = append(w.Webhooks, hook)
+	if err := w.save(); err != nil {
+		return Webhook{}, err
+	}
+
+	hook.Secret = ""
+	return hook, nil
+}
+
+// Delete removes a webhook
+func (w *WebhookStore) Delete(id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, hook := range w.Webhooks {
+		if hook.ID == id {
+			w.Webhooks = append(w.Webhooks[:i], w.Webhooks[i+1:]...)
+			return w.save()
+		}
+	}
+	return fmt.Errorf("webhook '%s' not found", id)
+}
+
+// Deliveries returns the logged deliveries, newest first, optionally limited
+// to one webhook
+func (w *WebhookStore) Deliveries(webhookID string) []WebhookDelivery {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	deliveries := []WebhookDelivery{}
+	for i := len(w.deliveries) - 1; i >= 0; i-- {
+		if webhookID == "" || w.deliveries[i].WebhookID == webhookID {
+			deliveries = append(deliveries, w.deliveries[i])
+		}
+	}
+	return deliveries
+}
+
+// Dispatch sends payloads to every webhook subscribed to them. Deliveries
+// run in the background so requests never wait for webhook receivers.
+func (w *WebhookStore) Dispatch(payloads []WebhookPayload) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	logged := false
+	for _, payload := range payloads {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			continue
+		}
+		for _, hook := range w.Webhooks {
+			if !hook.wants(payload.Type, payload.Repository) {
+				continue
+			}
+
+			delivery := WebhookDelivery{
+				ID:         payload.ID,
+				WebhookID:  hook.ID,
+				Event:      payload.Type,
+				Repository: payload.Repository,
+				Pending:    true,
+				CreatedAt:  time.Now(),
+				UpdatedAt:  time.Now(),
+			}
+			w.deliveries = append(w.deliveries, delivery)
+			if len(w.deliveries) > maxDeliveryLog {
+				w.deliveries = w.deliveries[len(w.deliveries)-maxDeliveryLog:]
+			}
+
+			logged = true
+
+			go w.deliver(hook, delivery, body)
+		}
+	}
+	if logged {
+		w.saveLog()
+	}
+}
+
+// deliver posts a payload, retrying failed attempts with exponential backoff
+func (w *WebhookStore) deliver(hook Webhook, delivery WebhookDelivery, body []byte) {
+	delay := webhookRetryDelay
+
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		delivery.Attempts = attempt
+		delivery.StatusCode = 0
+		delivery.Error = ""
+
+		statusCode, err := w.post(hook, delivery, body)
+		delivery.StatusCode = statusCode
+		if err == nil {
+			delivery.Delivered = true
+			delivery.Pending = false
+			w.record(delivery)
+			return
+		}
+
+		delivery.Error = err.Error()
+		delivery.Pending = attempt < webhookMaxAttempts
+		w.record(delivery)
+
+		if delivery.Pending {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+
+	fmt.Printf("Webhook %s gave up on %s after %d attempts: %s\n", hook.ID, delivery.Event, delivery.Attempts, delivery.Error)
+}
+
+// post sends one delivery attempt. Receivers verify the X-Todo-Signature
+// header, "sha256=" followed by the hex HMAC-SHA256 of the body.
+func (w *WebhookStore) post(hook Webhook, delivery WebhookDelivery, body []byte) (int, error) {
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-cli-server")
+	req.Header.Set("X-Todo-Event", delivery.Event)
+	req.Header.Set("X-Todo-Delivery", delivery.ID)
+	if hook.Secret != "" {
+		req.Header.Set("X-Todo-Signature", "sha256="+signPayload(hook.Secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// record updates a delivery in the log, if it is still there
+func (w *WebhookStore) record(delivery WebhookDelivery) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delivery.UpdatedAt = time.Now()
+	for i := range w.deliveries {
+		if w.deliveries[i].ID == delivery.ID && w.deliveries[i].WebhookID == delivery.WebhookID {
+			w.deliveries[i] = delivery
+			w.saveLog()
+			return
+		}
+	}
+}
+
+// signPayload returns the hex HMAC-SHA256 of a body
+func signPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// randomID returns a random hex identifier
+func randomID() string {
+	raw := make([]byte, 8)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
+
+// webhookPayloads derives the webhook events of a change to a repository
+func webhookPayloads(eventType, repoName, user string, previous, current *models.Repository) []WebhookPayload {
+	now := time.Now()
+	var payloads []WebhookPayload
+	add := func(eventType string, data interface{}) {
+		payloads = append(payloads, WebhookPayload{
+			ID:         randomID(),
+			Type:       eventType,
+			Repository: repoName,
+			User:       user,
+			Time:       now,
+			Data:       data,
+		})
+	}
+
+	if eventType == "push" {
+		add(WebhookPush, newEvent(eventType, repoName, user, previous, current))
+	}
+
+	previousBranches := make(map[string]map[int]models.Todo)
+	for _, branch := range previous.Branches {
+		todos := make(map[int]models.Todo)
+		for _, todo := range branch.Todos {
+			todos[todo.ID] = todo
+		}
+		previousBranches[branch.Name] = todos
+	}
+
+	for _, branch := range current.Branches {
+		todos, existed := previousBranches[branch.Name]
+		if !existed {
+			add(WebhookBranchCreated, models.BranchSummary{Name: branch.Name, Todos: len(branch.Todos)})
+			continue
+		}
+		for _, todo := range branch.Todos {
+			if before, ok := todos[todo.ID]; ok && before.Status != todo.Status {
+				add(WebhookTodoStatusChanged, TodoStatusChange{Branch: branch.Name, Todo: todo, PreviousStatus: before.Status})
+			}
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, commit := range previous.Commits {
+		known[commit.ID] = true
+	}
+
+	// 'todo merge' copies the source commits with a "[MERGED from <branch>]" prefix
+	var merges []BranchMerge
+	for _, commit := range current.Commits {
+		if known[commit.ID] {
+			continue
+		}
+		add(WebhookCommitCreated, models.CommitSummary{
+			ID:      commit.ID,
+			Message: commit.Message,
+			Branch:  commit.Branch,
+			Author:  commit.Author,
+		})
+
+		rest, ok := strings.CutPrefix(commit.Message, "[MERGED from ")
+		if !ok {
+			continue
+		}
+		source, _, ok := strings.Cut(rest, "]")
+		if !ok {
+			continue
+		}
+		if n := len(merges); n > 0 && merges[n-1].Source == source && merges[n-1].Target == commit.Branch {
+			merges[n-1].Commits++
+		} else {
+			merges = append(merges, BranchMerge{Source: source, Target: commit.Branch, Commits: 1})
+		}
+	}
+	for _, merge := range merges {
+		add(WebhookBranchMerged, merge)
+	}
+
+	return payloads
+}
+
+// publish announces a change to a hosted repository to event stream
+// subscribers and webhooks
+func (s *Server) publish(eventType, repoName, user string, previous, current *models.Repository) {
+	s.events.Publish(newEvent(eventType, repoName, user, previous, current))
+	if s.webhooks != nil {
+		s.webhooks.Dispatch(webhookPayloads(eventType, repoName, user, previous, current))
+	}
+}
+
+// handleAdminWebhooks lists (GET) and creates (POST) webhooks
+func (s *Server) handleAdminWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.webhooks.List())
+	case http.MethodPost:
+		var hook Webhook
+		if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+			http.Error(w, "Failed to parse request", http.StatusBadRequest)
+			return
+		}
+		if hook.Repository != "" {
+			if _, ok := s.repos.Get(hook.Repository); !ok {
+				http.Error(w, fmt.Sprintf("Repository '%s' not found", hook.Repository), http.StatusBadRequest)
+				return
+			}
+		}
+
+		created, err := s.webhooks.Create(hook)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fmt.Printf("Created webhook %s for %s\n", created.ID, created.URL)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminWebhook handles /admin/webhooks/<id> (DELETE),
+// /admin/webhooks/<id>/deliveries and /admin/webhooks/deliveries (GET)
+func (s *Server) handleAdminWebhook(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/webhooks/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "deliveries", len(parts) == 2 && parts[1] == "deliveries":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		webhookID := ""
+		if len(parts) == 2 {
+			webhookID = parts[0]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.webhooks.Deliveries(webhookID))
+	case len(parts) == 1:
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := s.webhooks.Delete(parts[0]); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		fmt.Printf("Deleted webhook %s\n", parts[0])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
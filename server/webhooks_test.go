@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+	"todo-cli/models"
+)
+
+// received is one request seen by the test receiver
+type received struct {
+	event     string
+	signature string
+	body      []byte
+}
+
+func TestWebhookDeliveryIsSignedAndLogged(t *testing.T) {
+	requests := make(chan received, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{event: r.Header.Get("X-Todo-Event"), signature: r.Header.Get("X-Todo-Signature"), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	dataDir := t.TempDir()
+	store, err := LoadWebhookStore(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook, err := store.Create(Webhook{URL: receiver.URL, Secret: "s3cret", Events: []string{WebhookBranchCreated}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := &models.Repository{Branches: []models.Branch{{Name: "main"}}}
+	current := &models.Repository{Branches: []models.Branch{{Name: "main"}, {Name: "feature"}}}
+	store.Dispatch(webhookPayloads("push", defaultRepo, "alice", previous, current))
+
+	var got received
+	select {
+	case got = <-requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	if got.event != WebhookBranchCreated {
+		t.Errorf("event = %q, want %q", got.event, WebhookBranchCreated)
+	}
+	if want := "sha256=" + signPayload("s3cret", got.body); got.signature != want {
+		t.Errorf("signature = %q, want %q", got.signature, want)
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.User != "alice" || payload.Repository != defaultRepo {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	// Only the subscribed event is sent
+	select {
+	case extra := <-requests:
+		t.Errorf("unexpected delivery of %q", extra.event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	waitForDelivery(t, store, hook.ID)
+
+	// The log survives a restart
+	reloaded, err := LoadWebhookStore(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries := reloaded.Deliveries(hook.ID)
+	if len(deliveries) != 1 || !deliveries[0].Delivered || deliveries[0].ID != payload.ID {
+		t.Errorf("reloaded deliveries = %+v", deliveries)
+	}
+}
+
+func TestWebhookLogMarksInterruptedDeliveriesFailed(t *testing.T) {
+	dataDir := t.TempDir()
+
+	// A log left behind by a server stopped while a delivery was retried
+	log := []WebhookDelivery{
+		{ID: "p1", WebhookID: "h1", Event: WebhookPush, Attempts: 2, Pending: true},
+		{ID: "p2", WebhookID: "h1", Event: WebhookPush, Attempts: 1, Delivered: true},
+	}
+	data, _ := json.Marshal(log)
+	if err := writeFileAtomic(filepath.Join(dataDir, historyDir, deliveryLogFile), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := LoadWebhookStore(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries := store.Deliveries("h1")
+	if len(deliveries) != 2 {
+		t.Fatalf("deliveries = %+v", deliveries)
+	}
+	if interrupted := deliveries[1]; interrupted.Pending || interrupted.Delivered || interrupted.Error == "" {
+		t.Errorf("interrupted delivery = %+v, want failed", interrupted)
+	}
+	if !deliveries[0].Delivered {
+		t.Errorf("delivered delivery = %+v", deliveries[0])
+	}
+}
+
+func TestWebhookPayloads(t *testing.T) {
+	previous := &models.Repository{
+		Branches: []models.Branch{{Name: "main", Todos: []models.Todo{{ID: 1, Title: "A", Status: "pending"}}}},
+		Commits:  []models.Commit{{ID: "c1", Branch: "main"}},
+	}
+	current := &models.Repository{
+		Branches: []models.Branch{
+			{Name: "main", Todos: []models.Todo{{ID: 1, Title: "A", Status: "completed"}}},
+			{Name: "feature"},
+		},
+		Commits: []models.Commit{
+			{ID: "c1", Branch: "main"},
+			{ID: "c2", Branch: "main", Message: "[MERGED from feature] one"},
+			{ID: "c3", Branch: "main", Message: "[MERGED from feature] two"},
+		},
+	}
+
+	counts := make(map[string]int)
+	for _, payload := range webhookPayloads("push", defaultRepo, "", previous, current) {
+		counts[payload.Type]++
+		if payload.Type == WebhookBranchMerged {
+			merge := payload.Data.(BranchMerge)
+			if merge.Source != "feature" || merge.Target != "main" || merge.Commits != 2 {
+				t.Errorf("merge = %+v", merge)
+			}
+		}
+	}
+
+	want := map[string]int{
+		WebhookPush:              1,
+		WebhookBranchCreated:     1,
+		WebhookTodoStatusChanged: 1,
+		WebhookCommitCreated:     2,
+		WebhookBranchMerged:      1,
+	}
+	for eventType, n := range want {
+		if counts[eventType] != n {
+			t.Errorf("%s events = %d, want %d", eventType, counts[eventType], n)
+		}
+	}
+}
+
+// waitForDelivery waits until the only delivery of a webhook succeeded
+func waitForDelivery(t *testing.T, store *WebhookStore, webhookID string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries := store.Deliveries(webhookID)
+		if len(deliveries) == 1 && deliveries[0].Delivered {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("delivery did not succeed: %+v", store.Deliveries(webhookID))
+}
@@ -0,0 +1,321 @@
+package storage
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todo-cli/models"
+)
+
+// Kinds of errors returned by the repository operations below, for callers
+// such as the server that map them to status codes
+var (
+	ErrNotFound = errors.New("not found")
+	ErrExists   = errors.New("already exists")
+	ErrRefused  = errors.New("refused")
+)
+
+// opError is an error with a readable message that matches its kind with
+// errors.Is
+type opError struct {
+	kind    error
+	message string
+}
+
+func (e *opError) Error() string { return e.message }
+func (e *opError) Unwrap() error { return e.kind }
+
+func opErrorf(kind error, format string, args ...interface{}) error {
+	return &opError{kind: kind, message: fmt.Sprintf(format, args...)}
+}
+
+// queries answers lookups that only read the repository value, so the
+// operations below work without a data directory
+var queries = &Storage{}
+
+// CreateBranch adds a branch named name. from is a branch or commit to start
+// from; an empty from creates a branch without todos or parent.
+func CreateBranch(repo *models.Repository, name, from string) (*models.Branch, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, opErrorf(ErrRefused, "branch name is required")
+	}
+	if queries.GetBranchByName(repo, name) != nil {
+		return nil, opErrorf(ErrExists, "branch '%s' already exists", name)
+	}
+
+	branch := models.Branch{
+		Name:      name,
+		CreatedAt: time.Now(),
+		Todos:     []models.Todo{},
+	}
+
+	if from != "" {
+		if parent := queries.GetBranchByName(repo, from); parent != nil {
+			branch = ForkBranch(repo, parent, name)
+		} else if commit := queries.GetCommitByID(repo, from); commit != nil {
+			todos, err := queries.GetTodosAtCommit(repo, commit.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, todo := range todos {
+				todo.BranchName = name
+				branch.Todos = append(branch.Todos, todo)
+			}
+			branch.Parent = commit.Branch
+			branch.ForkPoint = commit.ID
+			branch.Base = append([]models.Todo{}, branch.Todos...)
+		} else {
+			return nil, opErrorf(ErrNotFound, "'%s' is not a branch or commit", from)
+		}
+	}
+
+	repo.Branches = append(repo.Branches, branch)
+	return &repo.Branches[len(repo.Branches)-1], nil
+}
+
+// ForkBranch returns a new branch with a copy of parent's todos, recording the
+// fork point and merge base
+func ForkBranch(repo *models.Repository, parent *models.Branch, name string) models.Branch {
+	branch := models.Branch{
+		Name:      name,
+		CreatedAt: time.Now(),
+		Todos:     []models.Todo{},
+		Parent:    parent.Name,
+		ForkPoint: LastCommitID(repo, parent.Name),
+	}
+
+	for _, todo := range parent.Todos {
+		todo.BranchName = name
+		branch.Todos = append(branch.Todos, todo)
+	}
+	branch.Base = append([]models.Todo{}, branch.Todos...)
+
+	return branch
+}
+
+// LastCommitID returns the ID of the most recent commit on a branch, or "" if it has none
+func LastCommitID(repo *models.Repository, branchName string) string {
+	for i := len(repo.Commits) - 1; i >= 0; i-- {
+		if repo.Commits[i].Branch == branchName {
+			return repo.Commits[i].ID
+		}
+	}
+	return ""
+}
+
+// RenameBranch renames a branch and rewrites every reference to the old name,
+// including the branch names of todo states recorded in commits and merge
+// bases. Renaming main would drop its delete protection, so it needs force.
+func RenameBranch(repo *models.Repository, oldName, newName string, force bool) (*models.Branch, error) {
+	branch := queries.GetBranchByName(repo, oldName)
+	if branch == nil {
+		return nil, opErrorf(ErrNotFound, "branch '%s' does not exist", oldName)
+	}
+	if strings.TrimSpace(newName) == "" {
+		return nil, opErrorf(ErrRefused, "branch name is required")
+	}
+	if newName == oldName {
+		return branch, nil
+	}
+	if oldName == "main" && !force {
+		return nil, opErrorf(ErrRefused, "branch 'main' is protected, use --force to rename it")
+	}
+	if queries.GetBranchByName(repo, newName) != nil {
+		return nil, opErrorf(ErrExists, "branch '%s' already exists", newName)
+	}
+
+	branch.Name = newName
+	renameTodoBranch(branch.Todos, oldName, newName)
+	renameTodoBranch(branch.Base, oldName, newName)
+	for i := range repo.Commits {
+		if repo.Commits[i].Branch == oldName {
+			repo.Commits[i].Branch = newName
+		}
+		renameTodoBranch(repo.Commits[i].Snapshot, oldName, newName)
+		renameTodoBranch(repo.Commits[i].Before, oldName, newName)
+	}
+	for i := range repo.Branches {
+		if repo.Branches[i].Parent == oldName {
+			repo.Branches[i].Parent = newName
+		}
+		if mergeBase, ok := repo.Branches[i].MergeBases[oldName]; ok {
+			delete(repo.Branches[i].MergeBases, oldName)
+			repo.Branches[i].MergeBases[newName] = mergeBase
+		}
+		for _, todos := range repo.Branches[i].MergeBases {
+			renameTodoBranch(todos, oldName, newName)
+		}
+	}
+	if repo.CurrentBranch == oldName {
+		repo.CurrentBranch = newName
+	}
+
+	return branch, nil
+}
+
+// renameTodoBranch sets the branch name of todos on oldName to newName
+func renameTodoBranch(todos []models.Todo, oldName, newName string) {
+	for i := range todos {
+		if todos[i].BranchName == oldName {
+			todos[i].BranchName = newName
+		}
+	}
+}
+
+// DeleteBranch removes a branch and its commits from the repository. The main
+// branch and branches with todos missing from the current branch are only
+// deleted when force is set. The current branch and branches other branches
+// were created from are never deleted, since the children find their
+// committed todos through the parent's commits.
+func DeleteBranch(repo *models.Repository, name string, force bool) error {
+	branch := queries.GetBranchByName(repo, name)
+	if branch == nil {
+		return opErrorf(ErrNotFound, "branch '%s' does not exist", name)
+	}
+
+	if name == repo.CurrentBranch {
+		return opErrorf(ErrRefused, "cannot delete the current branch '%s'", name)
+	}
+
+	if name == "main" && !force {
+		return opErrorf(ErrRefused, "branch 'main' is protected, use --force to delete it")
+	}
+
+	for _, other := range repo.Branches {
+		if other.Parent == name {
+			return opErrorf(ErrRefused, "branch '%s' was created from '%s', delete it first", other.Name, name)
+		}
+	}
+
+	if !force {
+		currentBranch := queries.GetCurrentBranch(repo)
+		unmerged := 0
+		for _, todo := range branch.Todos {
+			if currentBranch == nil || queries.GetTodoByID(currentBranch, todo.ID) == nil {
+				unmerged++
+			}
+		}
+		if unmerged > 0 {
+			return opErrorf(ErrRefused, "branch '%s' has %d todos not merged into '%s', use --force to delete it anyway", name, unmerged, repo.CurrentBranch)
+		}
+	}
+
+	for i := range repo.Branches {
+		if repo.Branches[i].Name == name {
+			repo.Branches = append(repo.Branches[:i], repo.Branches[i+1:]...)
+			break
+		}
+	}
+	for i := range repo.Branches {
+		delete(repo.Branches[i].MergeBases, name)
+	}
+
+	var commits []models.Commit
+	for _, commit := range repo.Commits {
+		if commit.Branch != name {
+			commits = append(commits, commit)
+		}
+	}
+	repo.Commits = commits
+
+	return nil
+}
+
+// CommitCandidates returns the todo states the next commit on a branch
+// records: the staged todos if any are staged, otherwise every completed todo
+// changed since the last commit
+func CommitCandidates(repo *models.Repository, branch *models.Branch) []models.Todo {
+	if len(branch.Staged) == 0 {
+		return queries.GetUncommittedTodos(repo, branch.Name)
+	}
+
+	var snapshot []models.Todo
+	for _, id := range branch.Staged {
+		todo := queries.GetTodoByID(branch, id)
+		if todo != nil && todo.Status == "completed" {
+			snapshot = append(snapshot, *todo)
+		}
+	}
+	return snapshot
+}
+
+// PruneStaged unstages todos that were deleted or are no longer completed,
+// which no commit would record, and returns how many it unstaged
+func PruneStaged(branch *models.Branch) int {
+	var kept []int
+	for _, id := range branch.Staged {
+		todo := queries.GetTodoByID(branch, id)
+		if todo != nil && todo.Status == "completed" {
+			kept = append(kept, id)
+		}
+	}
+	pruned := len(branch.Staged) - len(kept)
+	branch.Staged = kept
+	return pruned
+}
+
+// NewCommit builds a commit for the given todo states, recording their
+// previous committed states so the commit can later be reverted
+func NewCommit(repo *models.Repository, branchName, message, author string, snapshot []models.Todo) models.Commit {
+	committed := queries.GetCommittedTodos(repo, branchName)
+
+	var todoIDs []int
+	var before []models.Todo
+	for _, todo := range snapshot {
+		todoIDs = append(todoIDs, todo.ID)
+		if previous, ok := committed[todo.ID]; ok {
+			before = append(before, previous)
+		}
+	}
+
+	return models.Commit{
+		ID:        GenerateCommitID(message, branchName),
+		Message:   message,
+		Branch:    branchName,
+		Todos:     todoIDs,
+		Snapshot:  snapshot,
+		Before:    before,
+		CreatedAt: time.Now(),
+		Author:    author,
+	}
+}
+
+// GenerateCommitID returns a short unique ID for a new commit
+func GenerateCommitID(message, branchName string) string {
+	hash := sha1.New()
+	hash.Write([]byte(fmt.Sprintf("%s-%s-%d", message, branchName, time.Now().UnixNano())))
+	return fmt.Sprintf("%x", hash.Sum(nil))[:8]
+}
+
+// DeleteCommit removes the newest commit of a branch, leaving its todos
+// uncommitted like 'todo reset' to the commit before it. Older commits and
+// commits other branches were created from cannot be deleted.
+func DeleteCommit(repo *models.Repository, id string) error {
+	commit := queries.GetCommitByID(repo, id)
+	if commit == nil {
+		return opErrorf(ErrNotFound, "commit '%s' not found", id)
+	}
+	if LastCommitID(repo, commit.Branch) != id {
+		return opErrorf(ErrRefused, "only the newest commit of branch '%s' can be deleted", commit.Branch)
+	}
+	for _, other := range repo.Commits {
+		if other.ID == id && other.Branch != commit.Branch {
+			return opErrorf(ErrRefused, "commit '%s' was merged into branch '%s'", id, other.Branch)
+		}
+	}
+	for _, branch := range repo.Branches {
+		if branch.ForkPoint == id {
+			return opErrorf(ErrRefused, "branch '%s' was created from commit '%s'", branch.Name, id)
+		}
+	}
+
+	for i := range repo.Commits {
+		if repo.Commits[i].ID == id {
+			repo.Commits = append(repo.Commits[:i], repo.Commits[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"todo-cli/models"
+)
+
+// renameTestRepository returns a repository whose feature branch appears in
+// todos, commits, merge bases and as a parent
+func renameTestRepository() *models.Repository {
+	todo := models.Todo{ID: 1, Title: "Ship", Status: "completed", BranchName: "feature"}
+	return &models.Repository{
+		Branches: []models.Branch{
+			{Name: "main", MergeBases: map[string][]models.Todo{"feature": {todo}}},
+			{Name: "feature", Parent: "main", Todos: []models.Todo{todo}, Base: []models.Todo{todo}},
+			{Name: "child", Parent: "feature"},
+		},
+		Commits: []models.Commit{
+			{ID: "c1", Branch: "feature", Todos: []int{1}, Snapshot: []models.Todo{todo}, Before: []models.Todo{todo}},
+		},
+		CurrentBranch: "feature",
+	}
+}
+
+func TestRenameBranchRewritesReferences(t *testing.T) {
+	repo := renameTestRepository()
+	if _, err := RenameBranch(repo, "feature", "topic", false); err != nil {
+		t.Fatal(err)
+	}
+
+	branch := repo.Branches[1]
+	if branch.Name != "topic" || branch.Todos[0].BranchName != "topic" || branch.Base[0].BranchName != "topic" {
+		t.Errorf("renamed branch = %+v", branch)
+	}
+	commit := repo.Commits[0]
+	if commit.Branch != "topic" || commit.Snapshot[0].BranchName != "topic" || commit.Before[0].BranchName != "topic" {
+		t.Errorf("commit = %+v, want every todo state on topic", commit)
+	}
+	mergeBase, ok := repo.Branches[0].MergeBases["topic"]
+	if !ok || mergeBase[0].BranchName != "topic" {
+		t.Errorf("merge bases = %+v", repo.Branches[0].MergeBases)
+	}
+	if repo.Branches[2].Parent != "topic" || repo.CurrentBranch != "topic" {
+		t.Errorf("parent = %s, current = %s, want topic", repo.Branches[2].Parent, repo.CurrentBranch)
+	}
+}
+
+func TestRenameMainNeedsForce(t *testing.T) {
+	repo := renameTestRepository()
+	if _, err := RenameBranch(repo, "main", "trunk", false); !errors.Is(err, ErrRefused) {
+		t.Errorf("renaming main = %v, want refused", err)
+	}
+	if _, err := RenameBranch(repo, "main", "trunk", true); err != nil {
+		t.Errorf("renaming main with force = %v", err)
+	}
+	if repo.Branches[0].Name != "trunk" || repo.Branches[1].Parent != "trunk" {
+		t.Errorf("branches = %+v", repo.Branches)
+	}
+}
+
+func TestRenameBranchInStates(t *testing.T) {
+	s := testStorage(t, nil)
+	if err := s.SaveRemoteState("origin", renameTestRepository()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveSyncBase("origin", renameTestRepository()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RenameBranchInStates("feature", "topic"); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := s.LoadRemoteState("origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := s.LoadSyncBase("origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, repo := range []*models.Repository{state, base} {
+		if s.GetBranchByName(repo, "topic") == nil || s.GetBranchByName(repo, "feature") != nil || repo.Commits[0].Branch != "topic" {
+			t.Errorf("saved state = %+v, want feature renamed to topic", repo)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"todo-cli/bundle"
+	"todo-cli/models"
+)
+
+// DataFiles returns every file in the data directory for a backup bundle.
+// Stored credentials and hooks are left out so bundles can be shared and
+// restoring one never installs programs to run, and git remote clones because
+// they can be fetched again.
+func (s *Storage) DataFiles() ([]bundle.File, error) {
+	var files []bundle.File
+
+	err := filepath.WalkDir(s.dataPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.dataPath, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() && (rel == gitCacheDir || rel == hooksDir) {
+			return fs.SkipDir
+		}
+		if !entry.Type().IsRegular() || localOnly(rel) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, bundle.File{Path: rel, Mode: info.Mode().Perm(), Data: data})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	return files, nil
+}
+
+// ReplaceDataFiles replaces the contents of the data directory with files and
+// returns how many were restored. Stored credentials and hooks are kept, and
+// hooks found in older bundles are ignored; credential helpers are cleared from
+// the restored remotes for the same reason. The files are written to a new
+// directory that then takes the place of the data directory, so a failed
+// restore leaves the current data as it was.
+func (s *Storage) ReplaceDataFiles(files []bundle.File) (int, error) {
+	info, err := os.Stat(s.dataPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read data directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(s.dataPath), filepath.Base(s.dataPath)+"-restore-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create restore directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if err := os.Chmod(staging, info.Mode().Perm()); err != nil {
+		return 0, fmt.Errorf("failed to create restore directory: %w", err)
+	}
+
+	restored := 0
+	for _, file := range files {
+		if localOnly(file.Path) {
+			continue
+		}
+		data := file.Data
+		if file.Path == repoFile {
+			if data, err = withoutCredentialHelpers(data); err != nil {
+				return 0, fmt.Errorf("failed to restore %s: %w", file.Path, err)
+			}
+		}
+		if err := writeDataFile(staging, file.Path, data, file.Mode); err != nil {
+			return 0, fmt.Errorf("failed to restore %s: %w", file.Path, err)
+		}
+		restored++
+	}
+
+	// Carry over this machine's credentials and hooks
+	err = filepath.WalkDir(s.dataPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.dataPath, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !localOnly(rel) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return writeDataFile(staging, rel, data, info.Mode().Perm())
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to keep local files: %w", err)
+	}
+
+	previous := staging + "-previous"
+	if err := os.Rename(s.dataPath, previous); err != nil {
+		return 0, fmt.Errorf("failed to replace data directory: %w", err)
+	}
+	if err := os.Rename(staging, s.dataPath); err != nil {
+		os.Rename(previous, s.dataPath)
+		return 0, fmt.Errorf("failed to replace data directory: %w", err)
+	}
+	os.RemoveAll(previous)
+
+	return restored, nil
+}
+
+// localOnly reports whether a slash-separated data file path belongs to this
+// machine and is never bundled or replaced by a restore
+func localOnly(rel string) bool {
+	first, _, _ := strings.Cut(rel, "/")
+	return rel == credentialsFile || first == hooksDir
+}
+
+// writeDataFile writes a slash-separated data file below dir
+func writeDataFile(dir, rel string, data []byte, mode os.FileMode) error {
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if mode == 0 {
+		mode = 0644
+	}
+	return os.WriteFile(path, data, mode)
+}
+
+// withoutCredentialHelpers clears the credential helpers of the remotes in
+// repository data, leaving data without helpers unchanged
+func withoutCredentialHelpers(data []byte) ([]byte, error) {
+	var repo models.Repository
+	if err := json.Unmarshal(data, &repo); err != nil {
+		return nil, err
+	}
+
+	cleared := false
+	for i := range repo.Remotes {
+		if repo.Remotes[i].CredentialHelper != "" {
+			repo.Remotes[i].CredentialHelper = ""
+			cleared = true
+		}
+	}
+	if !cleared {
+		return data, nil
+	}
+	return json.MarshalIndent(repo, "", "  ")
+}
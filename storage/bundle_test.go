@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"todo-cli/bundle"
+	"todo-cli/models"
+)
+
+// testStorage returns a storage with the given files in its data directory
+func testStorage(t *testing.T, files map[string]string) *Storage {
+	t.Helper()
+	s := &Storage{dataPath: filepath.Join(t.TempDir(), dataDir)}
+	if err := os.MkdirAll(s.dataPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for rel, data := range files {
+		if err := writeDataFile(s.dataPath, rel, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// readDataFile returns a data file's contents, or "" if it does not exist
+func readDataFile(t *testing.T, s *Storage, rel string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(s.dataPath, filepath.FromSlash(rel)))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestDataFilesLeavesOutLocalFiles(t *testing.T) {
+	s := testStorage(t, map[string]string{
+		repoFile:              "{}",
+		"remotes/origin.json": "{}",
+		credentialsFile:       "secret",
+		"hooks/pre-push":      "#!/bin/sh",
+		"git/origin/HEAD":     "ref",
+	})
+
+	files, err := s.DataFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	if len(paths) != 2 || paths[0] != "remotes/origin.json" || paths[1] != repoFile {
+		t.Errorf("bundled files = %v", paths)
+	}
+}
+
+func TestReplaceDataFiles(t *testing.T) {
+	s := testStorage(t, map[string]string{
+		repoFile:           "{}",
+		"remotes/old.json": "{}",
+		credentialsFile:    "secret",
+		"hooks/pre-push":   "local hook",
+	})
+
+	repo, _ := json.Marshal(models.Repository{Remotes: []models.Remote{{Name: "origin", CredentialHelper: "/tmp/evil"}}})
+	restored, err := s.ReplaceDataFiles([]bundle.File{
+		{Path: repoFile, Data: repo},
+		{Path: "remotes/origin.json", Data: []byte("{}")},
+		{Path: "hooks/pre-push", Data: []byte("bundled hook")},
+		{Path: "hooks/pre-commit", Data: []byte("bundled hook")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored != 2 {
+		t.Errorf("restored = %d, want 2", restored)
+	}
+
+	var got models.Repository
+	if err := json.Unmarshal([]byte(readDataFile(t, s, repoFile)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Remotes) != 1 || got.Remotes[0].CredentialHelper != "" {
+		t.Errorf("restored remotes = %+v, want the credential helper cleared", got.Remotes)
+	}
+
+	want := map[string]string{
+		"remotes/origin.json": "{}",
+		"remotes/old.json":    "",
+		credentialsFile:       "secret",
+		"hooks/pre-push":      "local hook",
+		"hooks/pre-commit":    "",
+	}
+	for rel, data := range want {
+		if got := readDataFile(t, s, rel); got != data {
+			t.Errorf("%s = %q, want %q", rel, got, data)
+		}
+	}
+}
+
+func TestReplaceDataFilesFailureKeepsData(t *testing.T) {
+	s := testStorage(t, map[string]string{repoFile: "current"})
+
+	// "remotes" cannot be both a file and a directory
+	_, err := s.ReplaceDataFiles([]bundle.File{
+		{Path: "remotes", Data: []byte("file")},
+		{Path: "remotes/origin.json", Data: []byte("{}")},
+		{Path: repoFile, Data: []byte("{}")},
+	})
+	if err == nil {
+		t.Fatal("restore succeeded")
+	}
+	if got := readDataFile(t, s, repoFile); got != "current" {
+		t.Errorf("%s = %q after failed restore", repoFile, got)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(s.dataPath))
+	if len(entries) != 1 {
+		t.Errorf("restore left %d entries next to the data directory", len(entries)-1)
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const credentialsFile = "credentials.json"
+
+// Credential holds the stored login for a remote URL
+type Credential struct {
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
+// CredentialKey returns the key of the stored credentials for a remote URL.
+// Like git, credentials belong to the URL rather than the remote's name, so a
+// token is never sent to another server after a remote is re-pointed.
+func CredentialKey(url string) string {
+	return strings.TrimRight(url, "/")
+}
+
+// LoadCredentials loads stored credentials keyed by CredentialKey
+func (s *Storage) LoadCredentials() (map[string]Credential, error) {
+	credentials := make(map[string]Credential)
+
+	data, err := os.ReadFile(filepath.Join(s.dataPath, credentialsFile))
+	if os.IsNotExist(err) {
+		return credentials, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	err = json.Unmarshal(data, &credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+
+	return credentials, nil
+}
+
+// SaveCredentials writes credentials readable only by the current user. They
+// go to a new file that is renamed into place, so the tokens are never
+// written under the mode of an older file.
+func (s *Storage) SaveCredentials(credentials map[string]Credential) error {
+	data, err := json.MarshalIndent(credentials, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+
+	// CreateTemp makes the file with mode 0600
+	tmp, err := os.CreateTemp(s.dataPath, credentialsFile+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(s.dataPath, credentialsFile))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+
+	return nil
+}
+
+// ForgetCredentials removes the stored credentials for a remote URL
+func (s *Storage) ForgetCredentials(url string) error {
+	credentials, err := s.LoadCredentials()
+	if err != nil {
+		return err
+	}
+	key := CredentialKey(url)
+	if _, ok := credentials[key]; !ok {
+		return nil
+	}
+	delete(credentials, key)
+	return s.SaveCredentials(credentials)
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveCredentialsIsPrivate(t *testing.T) {
+	// An older credentials file readable by everyone
+	s := testStorage(t, map[string]string{credentialsFile: "{}"})
+	path := filepath.Join(s.dataPath, credentialsFile)
+
+	credentials := map[string]Credential{CredentialKey("https://todo.example.com/"): {Username: "alice", Token: "secret"}}
+	if err := s.SaveCredentials(credentials); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("credentials mode = %o, want 0600", mode)
+	}
+	if entries, _ := os.ReadDir(s.dataPath); len(entries) != 1 {
+		t.Errorf("data directory = %v, want only the credentials file", entries)
+	}
+
+	loaded, err := s.LoadCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded["https://todo.example.com"]; got.Token != "secret" {
+		t.Errorf("loaded credentials = %+v", loaded)
+	}
+}
+
+func TestForgetCredentials(t *testing.T) {
+	s := testStorage(t, nil)
+	credentials := map[string]Credential{
+		"https://a.example.com": {Token: "a"},
+		"https://b.example.com": {Token: "b"},
+	}
+	if err := s.SaveCredentials(credentials); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ForgetCredentials("https://a.example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := s.LoadCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded["https://a.example.com"]; ok || loaded["https://b.example.com"].Token != "b" {
+		t.Errorf("credentials = %+v, want only b.example.com", loaded)
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const queueFile = "pending_pushes.json"
+
+// PendingPush is a push that failed because its remote was unreachable
+type PendingPush struct {
+	Remote    string    `json:"remote"`
+	QueuedAt  time.Time `json:"queued_at"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// LoadPendingPushes loads the offline push queue
+func (s *Storage) LoadPendingPushes() ([]PendingPush, error) {
+	var pending []PendingPush
+
+	data, err := os.ReadFile(filepath.Join(s.dataPath, queueFile))
+	if os.IsNotExist(err) {
+		return pending, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read push queue: %w", err)
+	}
+
+	err = json.Unmarshal(data, &pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse push queue: %w", err)
+	}
+
+	return pending, nil
+}
+
+// SavePendingPushes writes the offline push queue, removing the file once
+// the queue is empty
+func (s *Storage) SavePendingPushes(pending []PendingPush) error {
+	path := filepath.Join(s.dataPath, queueFile)
+	if len(pending) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear push queue: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal push queue: %w", err)
+	}
+
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write push queue: %w", err)
+	}
+
+	return nil
+}
@@ -5,13 +5,20 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
+	"todo-cli/diff"
 	"todo-cli/models"
 )
 
 const (
 	dataDir      = ".tododata"
 	repoFile     = "repository.json"
+	remotesDir   = "remotes"
+	syncBaseDir  = "syncbase"
+	hooksDir     = "hooks"
+	gitCacheDir  = "git"
 )
 
 // Storage handles data persistence
@@ -107,3 +114,235 @@ func (s *Storage) GetBranchByName(repo *models.Repository, name string) *models.
 	}
 	return nil
 }
+
+// GetTodoByID returns a todo from a branch by ID
+func (s *Storage) GetTodoByID(branch *models.Branch, id int) *models.Todo {
+	for i := range branch.Todos {
+		if branch.Todos[i].ID == id {
+			return &branch.Todos[i]
+		}
+	}
+	return nil
+}
+
+// GetCommittedTodos returns the last committed state of each todo on a branch
+func (s *Storage) GetCommittedTodos(repo *models.Repository, branchName string) map[int]models.Todo {
+	return s.committedTodos(repo, branchName, "")
+}
+
+// GetTodosAtCommit returns the committed todo states of a branch as of the given commit
+func (s *Storage) GetTodosAtCommit(repo *models.Repository, commitID string) ([]models.Todo, error) {
+	commit := s.GetCommitByID(repo, commitID)
+	if commit == nil {
+		return nil, fmt.Errorf("commit %s not found", commitID)
+	}
+
+	committed := s.committedTodos(repo, commit.Branch, commit.ID)
+
+	todos := make([]models.Todo, 0, len(committed))
+	for _, todo := range committed {
+		todos = append(todos, todo)
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		return todos[i].ID < todos[j].ID
+	})
+
+	return todos, nil
+}
+
+// GetCommitByID returns a commit by ID
+func (s *Storage) GetCommitByID(repo *models.Repository, commitID string) *models.Commit {
+	for i := range repo.Commits {
+		if repo.Commits[i].ID == commitID {
+			return &repo.Commits[i]
+		}
+	}
+	return nil
+}
+
+// committedTodos replays the commits of a branch, stopping after upTo if it is set.
+// Branches created from another branch start from the parent's state at the fork point.
+func (s *Storage) committedTodos(repo *models.Repository, branchName, upTo string) map[int]models.Todo {
+	return s.committedTodosDepth(repo, branchName, upTo, len(repo.Branches))
+}
+
+func (s *Storage) committedTodosDepth(repo *models.Repository, branchName, upTo string, depth int) map[int]models.Todo {
+	committed := make(map[int]models.Todo)
+	branch := s.GetBranchByName(repo, branchName)
+
+	// depth guards against parent cycles left behind by renames
+	if branch != nil && branch.Parent != "" && branch.ForkPoint != "" && depth > 0 {
+		committed = s.committedTodosDepth(repo, branch.Parent, branch.ForkPoint, depth-1)
+	}
+
+	for _, commit := range repo.Commits {
+		if commit.Branch != branchName {
+			continue
+		}
+
+		if len(commit.Snapshot) > 0 {
+			for _, todo := range commit.Snapshot {
+				committed[todo.ID] = todo
+			}
+		} else if branch != nil {
+			// Older commits only recorded IDs, so assume the current state was committed
+			for _, id := range commit.Todos {
+				if todo := s.GetTodoByID(branch, id); todo != nil {
+					committed[id] = *todo
+				}
+			}
+		}
+
+		if commit.ID == upTo {
+			break
+		}
+	}
+
+	return committed
+}
+
+// GetUncommittedTodos returns completed todos that changed since the branch's last commit
+func (s *Storage) GetUncommittedTodos(repo *models.Repository, branchName string) []models.Todo {
+	branch := s.GetBranchByName(repo, branchName)
+	if branch == nil {
+		return nil
+	}
+
+	committed := s.GetCommittedTodos(repo, branchName)
+
+	var changed []models.Todo
+	for _, todo := range branch.Todos {
+		if todo.Status != "completed" {
+			continue
+		}
+		if previous, ok := committed[todo.ID]; ok && !TodoChanged(previous, todo) {
+			continue
+		}
+		changed = append(changed, todo)
+	}
+
+	return changed
+}
+
+// TodoChanged reports whether the user-visible fields of a todo differ
+func TodoChanged(a, b models.Todo) bool {
+	return len(diff.FieldChanges(a, b)) > 0
+}
+
+// HookPath returns the path of a client-side hook executable
+func (s *Storage) HookPath(name string) string {
+	return filepath.Join(s.dataPath, hooksDir, name)
+}
+
+// GitCachePath returns the directory holding the working clone of a git remote
+func (s *Storage) GitCachePath(key string) string {
+	return filepath.Join(s.dataPath, gitCacheDir, key)
+}
+
+// SaveRemoteState stores the last fetched state of a remote for remote-tracking refs
+func (s *Storage) SaveRemoteState(remoteName string, repo *models.Repository) error {
+	return s.saveState(remotesDir, remoteName, "remote state", repo)
+}
+
+// LoadRemoteState loads the last fetched state of a remote
+func (s *Storage) LoadRemoteState(remoteName string) (*models.Repository, error) {
+	repo, err := s.loadState(remotesDir, remoteName, "remote state")
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no fetched state for remote '%s', run 'todo fetch %s' first", remoteName, remoteName)
+	}
+	return repo, err
+}
+
+// SaveSyncBase stores the state of a remote as of the last merge with it.
+// Unlike the remote state, which every fetch replaces, it only changes when
+// the remote was merged, so sync can tell which side changed a todo.
+func (s *Storage) SaveSyncBase(remoteName string, repo *models.Repository) error {
+	return s.saveState(syncBaseDir, remoteName, "sync base", repo)
+}
+
+// LoadSyncBase loads the state of a remote as of the last merge with it. The
+// error satisfies os.IsNotExist when the remote was never merged.
+func (s *Storage) LoadSyncBase(remoteName string) (*models.Repository, error) {
+	return s.loadState(syncBaseDir, remoteName, "sync base")
+}
+
+// RenameBranchInStates renames a branch in the saved remote states and sync
+// bases, so they keep matching the local repository after 'branch rename'
+func (s *Storage) RenameBranchInStates(oldName, newName string) error {
+	for _, dir := range []string{remotesDir, syncBaseDir} {
+		entries, err := os.ReadDir(filepath.Join(s.dataPath, dir))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s directory: %w", dir, err)
+		}
+		for _, entry := range entries {
+			remoteName, ok := strings.CutSuffix(entry.Name(), ".json")
+			if !ok || entry.IsDir() {
+				continue
+			}
+			repo, err := s.loadState(dir, remoteName, "remote state")
+			if err != nil {
+				return err
+			}
+			if s.GetBranchByName(repo, oldName) == nil || s.GetBranchByName(repo, newName) != nil {
+				continue
+			}
+			if _, err := RenameBranch(repo, oldName, newName, true); err != nil {
+				return err
+			}
+			if err := s.saveState(dir, remoteName, "remote state", repo); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// saveState writes a repository state of a remote to dir/<remote>.json
+func (s *Storage) saveState(dir, remoteName, what string, repo *models.Repository) error {
+	statePath := filepath.Join(s.dataPath, dir)
+	if err := os.MkdirAll(statePath, 0755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", dir, err)
+	}
+
+	data, err := json.MarshalIndent(repo, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", what, err)
+	}
+
+	err = os.WriteFile(filepath.Join(statePath, remoteName+".json"), data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", what, err)
+	}
+
+	return nil
+}
+
+// loadState reads a repository state of a remote from dir/<remote>.json,
+// returning the read error unwrapped when the file does not exist
+func (s *Storage) loadState(dir, remoteName, what string) (*models.Repository, error) {
+	data, err := os.ReadFile(filepath.Join(s.dataPath, dir, remoteName+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", what, err)
+	}
+
+	var repo models.Repository
+	err = json.Unmarshal(data, &repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", what, err)
+	}
+
+	return &repo, nil
+}
+
+// GetRemoteByName returns a remote by name
+func (s *Storage) GetRemoteByName(repo *models.Repository, name string) *models.Remote {
+	for i := range repo.Remotes {
+		if repo.Remotes[i].Name == name {
+			return &repo.Remotes[i]
+		}
+	}
+	return nil
+}